@@ -0,0 +1,31 @@
+//go:build !js
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"spaceinvader/internal/livehub"
+	"spaceinvader/internal/relay"
+	"strings"
+)
+
+// The relay of the online games, it also serves the spectator streams under /live/ for development
+func main() {
+	addr := flag.String("addr", ":8090", "address of the relay")
+	origins := flag.String("origins", "localhost:*", "comma separated host patterns of the pages the games are served from")
+	flag.Parse()
+	originPatterns := strings.Split(*origins, ",")
+
+	mux := http.NewServeMux()
+	mux.Handle("/", relay.New(originPatterns))
+	mux.Handle("/live/", http.StripPrefix("/live", livehub.New(originPatterns)))
+
+	fmt.Println("relay listening on", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
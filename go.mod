@@ -5,6 +5,7 @@ go 1.24.1
 require (
 	github.com/hajimehoshi/ebiten/v2 v2.8.7
 	golang.org/x/image v0.20.0
+	nhooyr.io/websocket v1.8.17
 )
 
 require (
@@ -91,6 +91,9 @@ func (g *Game) resize() {
 		// 20*20
 		{"internal/images/Objects/xff2.png", 20, 20},
 		{"internal/images/Objects/star3.png", 20, 20},
+		{"internal/images/Objects/plasma.png", 20, 20},
+		{"internal/images/Objects/pellet.png", 20, 20},
+		{"internal/images/Objects/seeker.png", 20, 20},
 	}
 
 	for _, imageData := range resizeInfo {
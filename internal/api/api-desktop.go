@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"spaceinvader/internal/defaultconfig"
 	"time"
 )
@@ -25,17 +26,32 @@ func New() APIClient {
 	return &desktopClient{}
 }
 
-func (c *desktopClient) AddScore(name string, score int) error {
+// AddScore submits a score to the leaderboard of the category, which is the difficulty played
+func (c *desktopClient) AddScore(name string, score int, category string) error {
 	data := map[string]any{
-		"name":  name,
-		"score": score,
+		"name":     name,
+		"score":    score,
+		"category": category,
 	}
+
+	return post(defaultconfig.ApiUrl+"invadd", data)
+}
+
+// AddAchievement submits an achievement of the player, the server keeps the unlocks of each name
+func (c *desktopClient) AddAchievement(name string, achievement string) error {
+	return post(defaultconfig.AchievementsUrl, map[string]any{
+		"name":        name,
+		"achievement": achievement,
+	})
+}
+
+func post(url string, data map[string]any) error {
 	requestBody, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("marshaling error: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", defaultconfig.ApiUrl+"invadd", bytes.NewBuffer(requestBody))
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return fmt.Errorf("request creation error: %w", err)
 	}
@@ -56,8 +72,10 @@ func (c *desktopClient) AddScore(name string, score int) error {
 	return nil
 }
 
-func (c *desktopClient) Top10() (UserScores, error) {
-	resp, err := http.Get(defaultconfig.ApiUrl + "invtop")
+// Top10 returns the best scores of the category
+func (c *desktopClient) Top10(category string) (UserScores, error) {
+	query := url.Values{"category": {category}}
+	resp, err := http.Get(defaultconfig.ApiUrl + "invtop?" + query.Encode())
 	if err != nil {
 		return nil, err
 	}
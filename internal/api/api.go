@@ -1,6 +1,8 @@
 package api
 
 type APIClient interface {
-	AddScore(name string, score int) error
-	Top10() (UserScores, error)
+	AddScore(name string, score int, category string) error
+	Top10(category string) (UserScores, error)
+	// AddAchievement records an achievement unlocked by the player on the score server
+	AddAchievement(name string, achievement string) error
 }
@@ -6,11 +6,20 @@ import (
 	"spaceinvader/internal/gametext"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
+// touchMargin widens the buttons for the fingers, which are less precise than the mouse
+const touchMargin = 8
+
 type Button interface {
 	New(caption string, x, y, w, h int, onClick func())
+	Rename(caption, newCaption string)
 	Remove(caption string)
+	// Navigate moves the focus to the nearest button in the direction, for the gamepad and the arrow keys
+	Navigate(dx, dy int)
+	// Press clicks the focused button, if any
+	Press()
 	Update()
 	Render(screen *ebiten.Image)
 }
@@ -26,6 +35,10 @@ type btnEvent struct {
 type btn struct {
 	mouseDown bool
 	buttons   map[string]*btnEvent
+	// focused is the caption of the button chosen without the mouse, it's shown as hovered
+	focused string
+	cursorX int
+	cursorY int
 }
 
 func New() Button {
@@ -35,29 +48,100 @@ func New() Button {
 }
 
 func (b *btn) New(caption string, x, y, w, h int, onClick func()) {
-	btnBg := ebiten.NewImage(w, h)
-	btnBg.Fill(color.RGBA{240, 33, 33, 255})
-
-	btnBgHover := ebiten.NewImage(w, h)
-	btnBgHover.Fill(color.RGBA{240, 120, 120, 255})
+	btn := &btnEvent{
+		x:       x,
+		y:       y,
+		w:       w,
+		h:       h,
+		onClick: onClick,
+	}
+	btn.renderCaption(caption)
+	b.buttons[caption] = btn
+}
 
-	textColor := color.RGBA{255, 255, 255, 255}
-	gametext.DrawWithColor(btnBg, caption, 5, 20, textColor)
+// Rename changes the caption of a button, keeping its position and click handler
+func (b *btn) Rename(caption, newCaption string) {
+	btn, ok := b.buttons[caption]
+	if !ok {
+		return
+	}
 
-	gametext.DrawWithColor(btnBgHover, caption, 5, 20, textColor)
-	b.buttons[caption] = &btnEvent{
-		background:      btnBg,
-		hoverBackground: btnBgHover,
-		x:               x,
-		y:               y,
-		w:               w,
-		h:               h,
-		onClick:         onClick,
+	delete(b.buttons, caption)
+	btn.renderCaption(newCaption)
+	b.buttons[newCaption] = btn
+	if b.focused == caption {
+		b.focused = newCaption
 	}
 }
 
 func (b *btn) Remove(caption string) {
 	delete(b.buttons, caption)
+	if b.focused == caption {
+		b.focused = ""
+	}
+}
+
+func (b *btn) Navigate(dx, dy int) {
+	current, ok := b.buttons[b.focused]
+	if !ok {
+		b.focused = b.first()
+		return
+	}
+
+	// The distance across the direction counts more, so the focus stays in the row or the column
+	best, bestDistance := "", 0
+	cx, cy := current.center()
+	for caption, btn := range b.buttons {
+		x, y := btn.center()
+		along := (x-cx)*dx + (y-cy)*dy
+		if along <= 0 {
+			continue
+		}
+
+		distance := along + 3*abs((x-cx)*dy-(y-cy)*dx)
+		if best == "" || distance < bestDistance || distance == bestDistance && caption < best {
+			best, bestDistance = caption, distance
+		}
+	}
+
+	if best != "" {
+		b.focused = best
+	}
+}
+
+func (b *btn) Press() {
+	if btn, ok := b.buttons[b.focused]; ok && btn.onClick != nil {
+		btn.onClick()
+	}
+}
+
+// first is the top left button, where the focus starts
+func (b *btn) first() string {
+	first := ""
+	for caption, btn := range b.buttons {
+		if f, ok := b.buttons[first]; !ok || btn.y < f.y || btn.y == f.y && btn.x < f.x {
+			first = caption
+		}
+	}
+
+	return first
+}
+
+func (btn *btnEvent) center() (int, int) {
+	return btn.x + btn.w/2, btn.y + btn.h/2
+}
+
+// contains reports if the point is on the button, or within margin around it
+func (btn *btnEvent) contains(x, y, margin int) bool {
+	return x >= btn.x-margin && x <= btn.x+btn.w+margin && y >= btn.y-margin && y <= btn.y+btn.h+margin
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
 }
 
 func (b *btn) Update() {
@@ -71,17 +155,39 @@ func (b *btn) Update() {
 		b.mouseDown = false
 	}
 
+	// The click handler runs after the loop, as it may add or remove buttons
+	var onClick func()
 	x, y := ebiten.CursorPosition()
+	// Moving the mouse takes over from the focus
+	if x != b.cursorX || y != b.cursorY {
+		b.focused = ""
+		b.cursorX, b.cursorY = x, y
+	}
+
 	for _, btn := range b.buttons {
-		if x >= btn.x && x <= btn.x+btn.w && y >= btn.y && y <= btn.y+btn.h {
+		if btn.contains(x, y, 0) {
 			btn.onHover = true
 			if justClicked && btn.onClick != nil {
-				btn.onClick()
+				onClick = btn.onClick
 			}
 		} else {
 			btn.onHover = false
 		}
 	}
+
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		b.focused = ""
+		tx, ty := ebiten.TouchPosition(id)
+		for _, btn := range b.buttons {
+			if btn.contains(tx, ty, touchMargin) && btn.onClick != nil {
+				onClick = btn.onClick
+			}
+		}
+	}
+
+	if onClick != nil {
+		onClick()
+	}
 }
 
 func (b *btn) Render(screen *ebiten.Image) {
@@ -90,10 +196,25 @@ func (b *btn) Render(screen *ebiten.Image) {
 	}
 }
 
+func (btn *btnEvent) renderCaption(caption string) {
+	btnBg := ebiten.NewImage(btn.w, btn.h)
+	btnBg.Fill(color.RGBA{240, 33, 33, 255})
+
+	btnBgHover := ebiten.NewImage(btn.w, btn.h)
+	btnBgHover.Fill(color.RGBA{240, 120, 120, 255})
+
+	textColor := color.RGBA{255, 255, 255, 255}
+	gametext.DrawWithColor(btnBg, caption, 5, 20, textColor)
+
+	gametext.DrawWithColor(btnBgHover, caption, 5, 20, textColor)
+	btn.background = btnBg
+	btn.hoverBackground = btnBgHover
+}
+
 func (b *btn) renderButton(screen *ebiten.Image, caption string, btn *btnEvent) {
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(float64(btn.x), float64(btn.y))
-	if btn.onHover {
+	if btn.onHover || caption == b.focused {
 		screen.DrawImage(btn.hoverBackground, op)
 		return
 	}
@@ -0,0 +1,172 @@
+package controls
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Bindings are the keys and the gamepad buttons of the first player's actions, the player can change them.
+// The controls share the maps, a change applies at once
+type Bindings struct {
+	Keys    map[Action][]ebiten.Key                   `json:"keys"`
+	Buttons map[Action][]ebiten.StandardGamepadButton `json:"buttons"`
+}
+
+// contexts are the actions used together, a key or a button can only be bound once in each.
+// Fire and Confirm share a button, as one is for the game and the other for the menus, the game is the first
+var contexts = [][]Action{
+	{Left, Right, Fire, Pause},
+	{Left, Right, Up, Down, Confirm},
+}
+
+// Bindable are the actions which can be rebound, in the order shown
+var Bindable = []Action{Left, Right, Fire, Pause, Up, Down, Confirm}
+
+var actionNames = map[Action]string{
+	Left:    "Left",
+	Right:   "Right",
+	Fire:    "Fire",
+	Pause:   "Pause",
+	Up:      "Up",
+	Down:    "Down",
+	Confirm: "Confirm",
+}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// NewBindings returns the default bindings
+func NewBindings() *Bindings {
+	b := &Bindings{
+		Keys:    map[Action][]ebiten.Key{},
+		Buttons: map[Action][]ebiten.StandardGamepadButton{},
+	}
+	b.Reset()
+
+	return b
+}
+
+// Reset puts the default bindings back
+func (b *Bindings) Reset() {
+	clear(b.Keys)
+	for action, keys := range keyMap {
+		b.Keys[action] = slices.Clone(keys)
+	}
+
+	clear(b.Buttons)
+	for action, buttons := range gamepadButtons {
+		b.Buttons[action] = slices.Clone(buttons)
+	}
+}
+
+// Load applies the saved bindings, the actions missing from them keep their bindings
+func (b *Bindings) Load(data []byte) error {
+	var saved Bindings
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("cannot decode bindings: %w", err)
+	}
+
+	maps.Copy(b.Keys, saved.Keys)
+	maps.Copy(b.Buttons, saved.Buttons)
+
+	return nil
+}
+
+// Conflict is the action an input is already bound to, Second is set for a key of the second player
+type Conflict struct {
+	Action Action
+	Second bool
+}
+
+func (c Conflict) String() string {
+	if c.Second {
+		return c.Action.String() + " of player 2"
+	}
+
+	return c.Action.String()
+}
+
+// BindKey binds the key to the action instead of its keys.
+// It returns the action the key is already bound to if they are used together, the binding is not changed then.
+// The keys of the second player are played at the same time as the game actions
+func (b *Bindings) BindKey(action Action, key ebiten.Key) (Conflict, bool) {
+	if other, ok := conflict(b.Keys, action, key); ok {
+		return Conflict{Action: other}, false
+	}
+
+	if slices.Contains(contexts[0], action) {
+		if other, ok := boundTo(secondKeyMap, key); ok {
+			return Conflict{Action: other, Second: true}, false
+		}
+	}
+
+	b.Keys[action] = []ebiten.Key{key}
+
+	return Conflict{Action: action}, true
+}
+
+// BindButton binds the gamepad button to the action instead of its buttons, as BindKey
+func (b *Bindings) BindButton(action Action, button ebiten.StandardGamepadButton) (Conflict, bool) {
+	if other, ok := conflict(b.Buttons, action, button); ok {
+		return Conflict{Action: other}, false
+	}
+
+	b.Buttons[action] = []ebiten.StandardGamepadButton{button}
+
+	return Conflict{Action: action}, true
+}
+
+// conflict returns the other action bound to the input and used together with the action
+func conflict[T comparable](bound map[Action][]T, action Action, input T) (Action, bool) {
+	for _, context := range contexts {
+		if !slices.Contains(context, action) {
+			continue
+		}
+
+		for _, other := range context {
+			if other != action && slices.Contains(bound[other], input) {
+				return other, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// boundTo returns the action the input is bound to
+func boundTo[T comparable](bound map[Action][]T, input T) (Action, bool) {
+	for action, inputs := range bound {
+		if slices.Contains(inputs, input) {
+			return action, true
+		}
+	}
+
+	return 0, false
+}
+
+// Glyph is the name of the key, button or pad of the first player for the action, as shown in the prompts
+func (b *Bindings) Glyph(device Device, action Action) string {
+	switch device {
+	case Gamepad:
+		if buttons := b.Buttons[action]; len(buttons) > 0 {
+			return ButtonName(buttons[0])
+		}
+	case Touch:
+		for _, pad := range touchPads {
+			if pad.Action == action {
+				return pad.Caption
+			}
+		}
+	default:
+		if keys := b.Keys[action]; len(keys) > 0 {
+			return keys[0].String()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,78 @@
+// Package controls maps the player input devices to game actions
+package controls
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+type Action int
+
+// Game actions
+const (
+	Left Action = iota
+	Right
+	Fire
+	Pause
+	// Up, Down and Confirm move between the menu buttons, Left and Right do it too
+	Up
+	Down
+	Confirm
+)
+
+type Controls interface {
+	Pressed(action Action) bool
+}
+
+type keyboard struct {
+	keys map[Action][]ebiten.Key
+}
+
+// keyMap are the default keys of the first player
+var keyMap = map[Action][]ebiten.Key{
+	Left:    {ebiten.KeyArrowLeft},
+	Right:   {ebiten.KeyArrowRight},
+	Fire:    {ebiten.KeySpace},
+	Pause:   {ebiten.KeyEscape, ebiten.KeyP},
+	Up:      {ebiten.KeyArrowUp},
+	Down:    {ebiten.KeyArrowDown},
+	Confirm: {ebiten.KeyEnter},
+}
+
+// secondKeyMap is on the other side of the keyboard, the first player pauses the game
+var secondKeyMap = map[Action][]ebiten.Key{
+	Left:  {ebiten.KeyA},
+	Right: {ebiten.KeyD},
+	Fire:  {ebiten.KeyW},
+}
+
+// New returns the controls of the first player, the keyboard and the first gamepad with the bindings, and the touch screen
+func New(bindings *Bindings) Controls {
+	return anyOf{&keyboard{keys: bindings.Keys}, &gamepad{index: 0, buttons: bindings.Buttons}, &touch{}}
+}
+
+// NewSecond returns the controls of the second player, sharing the keyboard or on the second gamepad.
+// The second gamepad has the buttons of the bindings, the keys of the second player are fixed
+func NewSecond(bindings *Bindings) Controls {
+	return anyOf{&keyboard{keys: secondKeyMap}, &gamepad{index: 1, buttons: bindings.Buttons}}
+}
+
+// anyOf is pressed when any of its controls is
+type anyOf []Controls
+
+func (a anyOf) Pressed(action Action) bool {
+	for _, c := range a {
+		if c.Pressed(action) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (k *keyboard) Pressed(action Action) bool {
+	for _, key := range k.keys[action] {
+		if ebiten.IsKeyPressed(key) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package controls
+
+import (
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+type Device int
+
+// Input devices, the mouse counts as the keyboard
+const (
+	Keyboard Device = iota
+	Gamepad
+	Touch
+)
+
+// Devices follows the gamepads plugged in and out, and the device used last for the on-screen prompts
+type Devices interface {
+	Update()
+	Last() Device
+	// Message tells about the gamepad connected or disconnected in the last update, it's empty otherwise
+	Message() string
+}
+
+type devices struct {
+	last      Device
+	connected []ebiten.GamepadID
+	message   string
+}
+
+func NewDevices() Devices {
+	return &devices{}
+}
+
+func (d *devices) Update() {
+	d.message = ""
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		d.connected = append(d.connected, id)
+		d.message = "Gamepad connected: " + ebiten.GamepadName(id)
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			d.message = "Gamepad not supported: " + ebiten.GamepadName(id)
+		}
+	}
+
+	d.connected = slices.DeleteFunc(d.connected, func(id ebiten.GamepadID) bool {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			d.message = "Gamepad disconnected"
+			return true
+		}
+
+		return false
+	})
+	if d.last == Gamepad && len(gamepads()) == 0 {
+		d.last = Keyboard
+	}
+
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		d.last = Keyboard
+	}
+
+	if gamepadUsed() {
+		d.last = Gamepad
+	}
+
+	if touchUsed() {
+		d.last = Touch
+	}
+}
+
+func (d *devices) Last() Device {
+	return d.last
+}
+
+func (d *devices) Message() string {
+	return d.message
+}
@@ -0,0 +1,136 @@
+package controls
+
+import (
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// deadzone of the analog sticks, smaller moves are ignored
+const deadzone = 0.25
+
+// gamepad is a gamepad with the standard layout, the one at index in the order they were connected
+type gamepad struct {
+	index   int
+	buttons map[Action][]ebiten.StandardGamepadButton
+}
+
+// gamepadButtons are the default buttons
+var gamepadButtons = map[Action][]ebiten.StandardGamepadButton{
+	Left:    {ebiten.StandardGamepadButtonLeftLeft},
+	Right:   {ebiten.StandardGamepadButtonLeftRight},
+	Up:      {ebiten.StandardGamepadButtonLeftTop},
+	Down:    {ebiten.StandardGamepadButtonLeftBottom},
+	Fire:    {ebiten.StandardGamepadButtonRightBottom, ebiten.StandardGamepadButtonFrontTopRight},
+	Pause:   {ebiten.StandardGamepadButtonCenterRight},
+	Confirm: {ebiten.StandardGamepadButtonRightBottom},
+}
+
+var buttonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "A",
+	ebiten.StandardGamepadButtonRightRight:       "B",
+	ebiten.StandardGamepadButtonRightLeft:        "X",
+	ebiten.StandardGamepadButtonRightTop:         "Y",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "LB",
+	ebiten.StandardGamepadButtonFrontTopRight:    "RB",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "LT",
+	ebiten.StandardGamepadButtonFrontBottomRight: "RT",
+	ebiten.StandardGamepadButtonCenterLeft:       "Back",
+	ebiten.StandardGamepadButtonCenterRight:      "Start",
+	ebiten.StandardGamepadButtonLeftStick:        "LS",
+	ebiten.StandardGamepadButtonRightStick:       "RS",
+	ebiten.StandardGamepadButtonLeftTop:          "Up",
+	ebiten.StandardGamepadButtonLeftBottom:       "Down",
+	ebiten.StandardGamepadButtonLeftLeft:         "Left",
+	ebiten.StandardGamepadButtonLeftRight:        "Right",
+	ebiten.StandardGamepadButtonCenterCenter:     "Home",
+}
+
+// ButtonName is the name of the button on an Xbox style gamepad
+func ButtonName(button ebiten.StandardGamepadButton) string {
+	return buttonNames[button]
+}
+
+type stickDirection struct {
+	axis ebiten.StandardGamepadAxis
+	sign float64
+}
+
+var gamepadSticks = map[Action]stickDirection{
+	Left:  {ebiten.StandardGamepadAxisLeftStickHorizontal, -1},
+	Right: {ebiten.StandardGamepadAxisLeftStickHorizontal, 1},
+	Up:    {ebiten.StandardGamepadAxisLeftStickVertical, -1},
+	Down:  {ebiten.StandardGamepadAxisLeftStickVertical, 1},
+}
+
+func (p *gamepad) Pressed(action Action) bool {
+	id, ok := gamepadAt(p.index)
+	if !ok {
+		return false
+	}
+
+	for _, button := range p.buttons[action] {
+		if ebiten.IsStandardGamepadButtonPressed(id, button) {
+			return true
+		}
+	}
+
+	stick, ok := gamepadSticks[action]
+
+	return ok && ebiten.StandardGamepadAxisValue(id, stick.axis)*stick.sign > deadzone
+}
+
+// gamepads returns the connected gamepads with the standard layout, the others can't be mapped
+func gamepads() []ebiten.GamepadID {
+	ids := []ebiten.GamepadID{}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+func gamepadAt(index int) (ebiten.GamepadID, bool) {
+	ids := gamepads()
+	if index >= len(ids) {
+		return 0, false
+	}
+
+	return ids[index], true
+}
+
+// gamepadUsed reports if a button of a gamepad was just pressed or a stick is pushed
+func gamepadUsed() bool {
+	for _, id := range gamepads() {
+		if len(inpututil.AppendJustPressedStandardGamepadButtons(id, nil)) > 0 {
+			return true
+		}
+
+		for _, stick := range gamepadSticks {
+			if ebiten.StandardGamepadAxisValue(id, stick.axis)*stick.sign > deadzone {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// JustPressedButton returns a button just pressed on the first gamepad, to bind it
+func JustPressedButton() (ebiten.StandardGamepadButton, bool) {
+	id, ok := gamepadAt(0)
+	if !ok {
+		return 0, false
+	}
+
+	buttons := inpututil.AppendJustPressedStandardGamepadButtons(id, nil)
+	if len(buttons) == 0 {
+		return 0, false
+	}
+
+	return buttons[0], true
+}
@@ -0,0 +1,53 @@
+package controls
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Pad is an on-screen touch pad, in the coordinates of the game screen
+type Pad struct {
+	Action     Action
+	Caption    string
+	X, Y, W, H int
+}
+
+// touchPads are the thumbs' places, moving on the left and firing on the right of the screen
+var touchPads = []Pad{
+	{Action: Left, Caption: "<", X: 20, Y: 396, W: 80, H: 68},
+	{Action: Right, Caption: ">", X: 110, Y: 396, W: 80, H: 68},
+	{Action: Fire, Caption: "FIRE", X: 520, Y: 390, W: 100, H: 74},
+	{Action: Pause, Caption: "||", X: 570, Y: 320, W: 50, H: 40},
+}
+
+// touch is the touch screen, only the pads are played so the taps on the HUD and the menus don't fire
+type touch struct{}
+
+// TouchPads returns the pads to draw when the touch screen is used
+func TouchPads() []Pad {
+	return touchPads
+}
+
+func (t *touch) Pressed(action Action) bool {
+	for _, id := range ebiten.AppendTouchIDs(nil) {
+		if pad, ok := padAt(ebiten.TouchPosition(id)); ok && pad.Action == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+func padAt(x, y int) (Pad, bool) {
+	for _, pad := range touchPads {
+		if x >= pad.X && x <= pad.X+pad.W && y >= pad.Y && y <= pad.Y+pad.H {
+			return pad, true
+		}
+	}
+
+	return Pad{}, false
+}
+
+func touchUsed() bool {
+	return len(inpututil.AppendJustPressedTouchIDs(nil)) > 0
+}
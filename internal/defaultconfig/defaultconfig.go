@@ -1,6 +1,8 @@
 // Package defaultconfig only contains default variables
 package defaultconfig
 
+import "strings"
+
 type AlibabaServiceType int
 
 // Default variables
@@ -11,6 +13,20 @@ const (
 	ApiUrl = "https://www.alirobo.fun/api/"
 )
 
+// Set at build time, see the Makefile
+var (
+	// RelayUrl is the relay server of the online games, there are no online games without it.
+	// The relay runs on ws://localhost:8090/ for development
+	RelayUrl = ""
+	// LiveUrl lists the live games, the games are neither watched nor broadcast without it.
+	// The relay serves them for development on http://localhost:8090/live/
+	LiveUrl = ""
+	// LiveSocketUrl streams the live games
+	LiveSocketUrl = socketUrl(LiveUrl)
+	// AchievementsUrl receives the achievements of the players, they are only kept locally without it
+	AchievementsUrl = ""
+)
+
 // Events
 const (
 	_ AlibabaServiceType = iota
@@ -26,6 +42,7 @@ const (
 )
 
 var ServiceDescriptionMap = map[AlibabaServiceType]string{
+	BossRom:              "Boss ROM",
 	Ecs:                  "ECS Elastic container service",
 	FunctionCompute:      "Function compute",
 	ServerlessComputing:  "Serverless Compute",
@@ -35,3 +52,16 @@ var ServiceDescriptionMap = map[AlibabaServiceType]string{
 	Cdn:                  "CDN",
 	ApsaraDB:             "ApsaraDB Database storage",
 }
+
+// socketUrl is the WebSocket address of the url, secure on https
+func socketUrl(url string) string {
+	if rest, ok := strings.CutPrefix(url, "https://"); ok {
+		return "wss://" + rest
+	}
+
+	if rest, ok := strings.CutPrefix(url, "http://"); ok {
+		return "ws://" + rest
+	}
+
+	return url
+}
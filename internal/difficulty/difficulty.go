@@ -0,0 +1,61 @@
+// Package difficulty scales the level data to the chosen game mode
+package difficulty
+
+import (
+	"math"
+	"spaceinvader/internal/levels"
+)
+
+// Difficulty multiplies the pace of the levels, 1 keeps the level file value
+type Difficulty struct {
+	Name      string
+	Lives     int
+	Speed     float64
+	SpeedUp   float64
+	BombRate  float64
+	BombSpeed float64
+	// Invulnerability is how many frames the player can't be hit after respawning
+	Invulnerability int
+	// Cooldown is the least number of frames between two shots of the player
+	Cooldown int
+	// MaxBullets is how many bullets of the player can be on the screen
+	MaxBullets int
+}
+
+// Game modes
+var (
+	Easy   = Difficulty{Name: "easy", Lives: 5, Speed: 0.8, SpeedUp: 0.7, BombRate: 0.7, BombSpeed: 0.8, Invulnerability: 180, Cooldown: 8, MaxBullets: 8}
+	Normal = Difficulty{Name: "normal", Lives: 3, Speed: 1, SpeedUp: 1, BombRate: 1, BombSpeed: 1, Invulnerability: 120, Cooldown: 12, MaxBullets: 6}
+	Hard   = Difficulty{Name: "hard", Lives: 2, Speed: 1.25, SpeedUp: 1.3, BombRate: 1.5, BombSpeed: 1.3, Invulnerability: 90, Cooldown: 16, MaxBullets: 4}
+	Custom = Difficulty{Name: "custom", Lives: 3, Speed: 1, SpeedUp: 1, BombRate: 1, BombSpeed: 1, Invulnerability: 120, Cooldown: 12, MaxBullets: 6}
+)
+
+// Presets returns the selectable game modes, the custom one is changed by the player
+func Presets() []Difficulty {
+	return []Difficulty{Easy, Normal, Hard}
+}
+
+// Apply returns the level scaled to the difficulty
+func (d Difficulty) Apply(level levels.Level) levels.Level {
+	level.Speed *= d.Speed
+	level.SpeedUp *= d.SpeedUp
+	level.BombSpeed *= d.BombSpeed
+
+	// A higher bomb rate means a shorter delay between the bombs
+	level.FirstBombDelay = scaleDelay(level.FirstBombDelay, d.BombRate)
+	level.BombDelayMin = scaleDelay(level.BombDelayMin, d.BombRate)
+	level.BombDelayMax = max(scaleDelay(level.BombDelayMax, d.BombRate), level.BombDelayMin+1)
+
+	if level.Boss != nil {
+		boss := *level.Boss
+		boss.Speed *= d.Speed
+		boss.AttackDelay = max(scaleDelay(boss.AttackDelay, d.BombRate), 1)
+		level.Boss = &boss
+	}
+
+	return level
+}
+
+func scaleDelay(delay int, rate float64) int {
+	return int(math.Round(float64(delay) / rate))
+}
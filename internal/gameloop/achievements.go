@@ -0,0 +1,248 @@
+package gameloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"slices"
+	"spaceinvader/internal/api"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/hud"
+	"spaceinvader/internal/storage"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	achievementsKey = "achievements"
+	// toastFrames is how long an unlocked achievement is shown
+	toastFrames = 180
+	// endlessGoal is the endless wave to reach for its achievement
+	endlessGoal = 5
+)
+
+type achievementEvent int
+
+// The game events the achievements listen to
+const (
+	achievementKill achievementEvent = iota
+	achievementBossDefeated
+	// achievementLevelCleared comes before the shots of the level are reset
+	achievementLevelCleared
+	achievementLevelStarted
+)
+
+// achievement is unlocked on its event when the game has reached it
+type achievement struct {
+	id          string
+	name        string
+	description string
+	event       achievementEvent
+	reached     func(g *game) bool
+}
+
+var achievementList = []achievement{
+	{
+		id:          "first-kill",
+		name:        "First contact",
+		description: "Destroy an invader",
+		event:       achievementKill,
+	},
+	{
+		id:          "flawless-level",
+		name:        "Untouchable",
+		description: "Clear a level without losing a life",
+		event:       achievementLevelCleared,
+		reached: func(g *game) bool {
+			return g.scoring.livesLost == 0
+		},
+	},
+	{
+		id:          "perfect-accuracy",
+		name:        "Sharpshooter",
+		description: "Clear a level without missing a shot",
+		event:       achievementLevelCleared,
+		reached: func(g *game) bool {
+			return g.scoring.shots > 0 && g.scoring.hits == g.scoring.shots
+		},
+	},
+	{
+		id:          "boss",
+		name:        "Giant slayer",
+		description: "Destroy a boss",
+		event:       achievementBossDefeated,
+	},
+	{
+		id:          "endless",
+		name:        "Endless defender",
+		description: fmt.Sprintf("Reach the endless wave %d", endlessGoal),
+		event:       achievementLevelStarted,
+		reached: func(g *game) bool {
+			return g.isEndless() && g.level-len(g.levels.Levels) >= endlessGoal
+		},
+	},
+}
+
+// achievements keeps the unlocked achievements and the ones sent to the score server
+type achievements struct {
+	storage  storage.Storage
+	unlocked map[string]bool
+	synced   map[string]bool
+	// toasts are the achievements unlocked to show, the first one is shown for toastFrames
+	toasts []achievement
+	timer  int
+}
+
+type savedAchievements struct {
+	Unlocked []string `json:"unlocked"`
+	Synced   []string `json:"synced"`
+}
+
+func loadAchievements(store storage.Storage) *achievements {
+	a := &achievements{
+		storage:  store,
+		unlocked: map[string]bool{},
+		synced:   map[string]bool{},
+	}
+
+	data, err := store.Load(achievementsKey)
+	if err != nil || data == nil {
+		if err != nil {
+			fmt.Println(err)
+		}
+		return a
+	}
+
+	var saved savedAchievements
+	if err := json.Unmarshal(data, &saved); err != nil {
+		fmt.Println("cannot decode achievements:", err)
+		return a
+	}
+
+	for _, id := range saved.Unlocked {
+		a.unlocked[id] = true
+	}
+	for _, id := range saved.Synced {
+		a.synced[id] = true
+	}
+
+	return a
+}
+
+func (a *achievements) save() {
+	saved := savedAchievements{}
+	for _, item := range achievementList {
+		if a.unlocked[item.id] {
+			saved.Unlocked = append(saved.Unlocked, item.id)
+		}
+		if a.synced[item.id] {
+			saved.Synced = append(saved.Synced, item.id)
+		}
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := a.storage.Save(achievementsKey, data); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// achieve unlocks the achievements of the event reached by the game.
+// The replays and the simulation have no achievements, only the player's own games unlock them
+func (g *game) achieve(event achievementEvent) {
+	a := g.achievements
+	if a == nil {
+		return
+	}
+
+	for _, item := range achievementList {
+		if item.event != event || a.unlocked[item.id] || item.reached != nil && !item.reached(g) {
+			continue
+		}
+
+		a.unlocked[item.id] = true
+		a.toasts = append(a.toasts, item)
+		a.save()
+	}
+}
+
+// sync sends the unlocks not sent yet to the score server, with the name of the saved score
+func (a *achievements) sync(client api.APIClient, name string) {
+	changed := false
+	for _, item := range achievementList {
+		if !a.unlocked[item.id] || a.synced[item.id] {
+			continue
+		}
+
+		if err := client.AddAchievement(name, item.id); err != nil {
+			fmt.Println(err)
+			break
+		}
+		a.synced[item.id] = true
+		changed = true
+	}
+
+	if changed {
+		a.save()
+	}
+}
+
+func (g *game) initiateAchievementsButtons() {
+	g.openScreenButtons.New("Achievements", 475, 360, 130, 28, func() {
+		g.drawStatus = statusDrawAchievements
+	})
+
+	g.achieveButtons = button.New()
+	g.achieveButtons.New("Back", 290, 400, 60, 28, func() {
+		g.drawStatus = statusDrawIntro
+	})
+}
+
+// drawAchievementToast shows the achievements just unlocked one after the other, over every screen
+func (g *game) drawAchievementToast(screen *ebiten.Image) {
+	a := g.achievements
+	if len(a.toasts) == 0 {
+		return
+	}
+
+	hud.DrawToast(screen, "Achievement unlocked", a.toasts[0].name, 20, 60)
+	a.timer++
+	if a.timer >= toastFrames {
+		a.timer = 0
+		a.toasts = slices.Delete(a.toasts, 0, 1)
+	}
+}
+
+func (g *game) drawAchievements(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(g.images.titleImage, op)
+	screen.DrawImage(g.images.overlay, op)
+
+	unlocked := 0
+	for _, item := range achievementList {
+		if g.achievements.unlocked[item.id] {
+			unlocked++
+		}
+	}
+	gametext.Draw(screen, fmt.Sprintf("ACHIEVEMENTS - %d/%d", unlocked, len(achievementList)), 230, 50)
+
+	locked := color.RGBA{120, 120, 120, 255}
+	for i, item := range achievementList {
+		y := float64(110 + i*50)
+		if !g.achievements.unlocked[item.id] {
+			gametext.DrawWithColor(screen, item.name, 150, y, locked)
+			gametext.DrawWithColor(screen, item.description, 150, y+22, locked)
+			continue
+		}
+
+		gametext.DrawWithColor(screen, item.name, 150, y, bonusColor)
+		gametext.Draw(screen, item.description, 150, y+22)
+	}
+
+	g.achieveButtons.Render(screen)
+}
@@ -0,0 +1,119 @@
+package gameloop
+
+import (
+	"maps"
+	"slices"
+	"spaceinvader/internal/levels"
+	"spaceinvader/internal/sprite"
+)
+
+const (
+	// diveChance is one in how many frames a diver may leave the formation
+	diveChance = 90
+	maxDivers  = 2
+	diveFrames = 150
+	// diveDepth is the lowest point of a dive, right above the player
+	diveDepth = ScreenH - 90
+)
+
+// dive is the path of a diver out of the formation and back to its slot
+type dive struct {
+	frame int
+	// controlX and controlY are the control point of the curve, set so it passes over the player
+	controlX, controlY float64
+}
+
+// ufoCollision destroys a diving ufo which crashes into a robot
+func (u *ufos) ufoCollision(ufo sprite.Sprite, players []sprite.Sprite) {
+	if u.dives[ufo.Id()] == nil || ufo.InAfterAnimation() {
+		return
+	}
+
+	if u.hitPlayers(players) {
+		delete(u.dives, ufo.Id())
+		ufo.RunAfterAnimation()
+	}
+}
+
+// startDive sends a random diver of the formation towards the nearest robot
+func (u *ufos) startDive() {
+	if len(u.dives) >= maxDivers || u.rnd.IntN(diveChance) != 0 {
+		return
+	}
+
+	candidates := []int{}
+	for _, id := range slices.Sorted(maps.Keys(u.ufoList)) {
+		diver := id < minionIdStart && u.types[id].Behavior == levels.BehaviorDiver
+		if diver && u.dives[id] == nil && !u.ufoList[id].InAfterAnimation() {
+			candidates = append(candidates, id)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return
+	}
+
+	id := candidates[u.rnd.IntN(len(candidates))]
+	ufo := u.ufoList[id]
+	player := u.nearestPlayer(centerX(ufo))
+	if player == nil {
+		return
+	}
+
+	targetX := centerX(player) - ufo.GetWidth()/2
+	u.dives[id] = &dive{
+		controlX: 2*targetX - ufo.GetX(),
+		controlY: 2*diveDepth - ufo.GetY(),
+	}
+	ufo.Soft(false)
+}
+
+// renderDive moves a diver along a curve starting and ending at its slot of the formation
+func (u *ufos) renderDive(ufo sprite.Sprite, slotX, slotY float64) {
+	d := u.dives[ufo.Id()]
+	d.frame++
+	if d.frame >= diveFrames {
+		delete(u.dives, ufo.Id())
+		ufo.SetX(clamp(slotX, 0, ScreenW-ufo.GetWidth()))
+		ufo.SetY(slotY)
+		ufo.Soft(true)
+		return
+	}
+
+	// A quadratic curve with the same start and end point
+	t := float64(d.frame) / diveFrames
+	slot := (1-t)*(1-t) + t*t
+	control := 2 * (1 - t) * t
+	ufo.SetX(clamp(slot*slotX+control*d.controlX, 0, ScreenW-ufo.GetWidth()))
+	ufo.SetY(clamp(slot*slotY+control*d.controlY, 0, ScreenH-ufo.GetHeight()))
+}
+
+// armourHolds takes the hits of an armoured ship, it reports if the ship survives and shows its damage
+func (u *ufos) armourHolds(ufo sprite.Sprite, hits int) bool {
+	enemy := u.types[ufo.Id()]
+	if enemy.Behavior != levels.BehaviorArmoured {
+		return false
+	}
+
+	u.damage[ufo.Id()] += hits
+	hits = u.damage[ufo.Id()]
+	if hits >= enemy.Health {
+		return false
+	}
+
+	if len(enemy.DamageImages) > 0 {
+		ufo.SetImages(enemy.DamageImages[min(hits, len(enemy.DamageImages))-1])
+	}
+
+	return true
+}
+
+// bombTarget is where the bomb of the ufo falls, the shooters aim at the nearest robot
+func (u *ufos) bombTarget(ufo sprite.Sprite) float64 {
+	player := u.nearestPlayer(centerX(ufo))
+	if u.types[ufo.Id()].Behavior != levels.BehaviorShooter || player == nil {
+		return ufo.GetX()
+	}
+
+	return clamp(centerX(player)-10, 0, ScreenW-20)
+}
@@ -0,0 +1,105 @@
+package gameloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"spaceinvader/internal/storage"
+	"sync"
+)
+
+const bestKey = "best"
+
+// bestScores are the local best of each score category and the top of the current leaderboard
+type bestScores struct {
+	// storage is nil when nothing is persisted, as in the simulation
+	storage storage.Storage
+	local   map[string]int
+	mu      sync.Mutex
+	// global is loaded in the background, it's zero until then
+	global int
+	// fetches counts the loads of global, only the answer to the last one is kept
+	fetches int
+}
+
+func loadBestScores(store storage.Storage) *bestScores {
+	b := &bestScores{
+		storage: store,
+		local:   map[string]int{},
+	}
+	if store == nil {
+		return b
+	}
+
+	data, err := store.Load(bestKey)
+	if err != nil {
+		fmt.Println(err)
+		return b
+	}
+
+	if data != nil {
+		if err := json.Unmarshal(data, &b.local); err != nil {
+			fmt.Println("cannot decode best scores:", err)
+		}
+	}
+
+	return b
+}
+
+// fetchGlobalBest loads the top score of the current category without blocking the game
+func (g *game) fetchGlobalBest() {
+	b := g.best
+	b.mu.Lock()
+	b.global = 0
+	b.fetches++
+	fetch := b.fetches
+	b.mu.Unlock()
+	if g.api == nil {
+		return
+	}
+
+	category := g.scoreCategory()
+	go func() {
+		scores, err := g.api.Top10(category)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		// A slower answer for an earlier category is dropped
+		if len(scores) > 0 && fetch == b.fetches {
+			b.global = scores[0].Score
+		}
+	}()
+}
+
+func (b *bestScores) globalBest() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.global
+}
+
+// saveLocalBest keeps the score if it beats the local best of the category
+func (g *game) saveLocalBest() {
+	category := g.scoreCategory()
+	if g.score <= g.best.local[category] {
+		return
+	}
+
+	g.best.local[category] = g.score
+	if g.best.storage == nil {
+		return
+	}
+
+	data, err := json.Marshal(g.best.local)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := g.best.storage.Save(bestKey, data); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,193 @@
+package gameloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/storage"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	bindingsKey = "bindings"
+	// rebindFrames is how long a new key or button is waited for, five seconds
+	rebindFrames = 300
+)
+
+// rebind is the controls screen, waiting for the new key or button of an action once one is clicked
+type rebind struct {
+	waiting bool
+	action  controls.Action
+	device  controls.Device
+	timer   int
+	message string
+	// back is the screen the controls screen was opened from
+	back drawStatus
+}
+
+func loadBindings(store storage.Storage) *controls.Bindings {
+	bindings := controls.NewBindings()
+	data, err := store.Load(bindingsKey)
+	if err != nil {
+		fmt.Println(err)
+		return bindings
+	}
+
+	if data != nil {
+		if err := bindings.Load(data); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	return bindings
+}
+
+func (g *game) saveBindings() {
+	data, err := json.Marshal(g.bindings)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := g.storage.Save(bindingsKey, data); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (g *game) initiateControlsButtons() {
+	g.openScreenButtons.New("Controls", 185, 320, 130, 28, func() {
+		g.openControls(statusDrawIntro)
+	})
+	g.settingsButtons.New("Controls", 330, 230, 210, 28, func() {
+		g.openControls(statusDrawSettings)
+	})
+
+	g.controlsButtons = button.New()
+	for i, action := range controls.Bindable {
+		y := 90 + i*36
+		g.controlsButtons.New(g.keyCaption(action), 105, y, 200, 28, func() {
+			g.startRebind(action, controls.Keyboard)
+		})
+		g.controlsButtons.New(g.buttonCaption(action), 335, y, 200, 28, func() {
+			g.startRebind(action, controls.Gamepad)
+		})
+	}
+	g.controlsButtons.New("Reset defaults", 105, 400, 150, 28, func() {
+		g.updateBindings(g.bindings.Reset)
+		g.rebind.message = "The default controls are back"
+	})
+	g.controlsButtons.New("Back", 335, 400, 80, 28, func() {
+		g.drawStatus = g.rebind.back
+	})
+}
+
+// The captions have the action, as two actions used apart may share a key
+func (g *game) keyCaption(action controls.Action) string {
+	return action.String() + ": " + g.bindings.Glyph(controls.Keyboard, action)
+}
+
+func (g *game) buttonCaption(action controls.Action) string {
+	return action.String() + ": pad " + g.bindings.Glyph(controls.Gamepad, action)
+}
+
+func (g *game) openControls(back drawStatus) {
+	g.rebind = rebind{back: back}
+	g.drawStatus = statusDrawControls
+}
+
+func (g *game) startRebind(action controls.Action, device controls.Device) {
+	g.rebind.waiting = true
+	g.rebind.action = action
+	g.rebind.device = device
+	g.rebind.timer = rebindFrames
+	g.rebind.message = "Press a key for " + action.String() + ", click to cancel"
+	if device == controls.Gamepad {
+		g.rebind.message = "Press a gamepad button for " + action.String() + ", click to cancel"
+	}
+}
+
+// updateControls binds the key or button pressed while waiting, the buttons work otherwise
+func (g *game) updateControls() {
+	r := &g.rebind
+	if !r.waiting {
+		g.updateButtons(g.controlsButtons)
+		return
+	}
+
+	r.timer--
+	if r.timer <= 0 || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) || len(inpututil.AppendJustPressedTouchIDs(nil)) > 0 {
+		r.waiting = false
+		r.message = ""
+		return
+	}
+
+	var bound controls.Conflict
+	var ok bool
+	var name string
+	switch r.device {
+	case controls.Gamepad:
+		pressed, found := controls.JustPressedButton()
+		if !found {
+			return
+		}
+		name = controls.ButtonName(pressed)
+		g.updateBindings(func() {
+			bound, ok = g.bindings.BindButton(r.action, pressed)
+		})
+	default:
+		keys := inpututil.AppendJustPressedKeys(nil)
+		if len(keys) == 0 {
+			return
+		}
+		name = keys[0].String()
+		g.updateBindings(func() {
+			bound, ok = g.bindings.BindKey(r.action, keys[0])
+		})
+	}
+
+	r.waiting = false
+	r.message = ""
+	if !ok {
+		r.message = name + " is already bound to " + bound.String()
+	}
+}
+
+// updateBindings changes the bindings, then renames the buttons and saves them
+func (g *game) updateBindings(change func()) {
+	keyCaptions := map[controls.Action]string{}
+	buttonCaptions := map[controls.Action]string{}
+	for _, action := range controls.Bindable {
+		keyCaptions[action] = g.keyCaption(action)
+		buttonCaptions[action] = g.buttonCaption(action)
+	}
+
+	change()
+
+	for _, action := range controls.Bindable {
+		g.controlsButtons.Rename(keyCaptions[action], g.keyCaption(action))
+		g.controlsButtons.Rename(buttonCaptions[action], g.buttonCaption(action))
+	}
+
+	g.saveBindings()
+}
+
+func (g *game) drawControls(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	background := g.images.titleImage
+	// The game is behind the menus opened from the pause screen
+	if g.rebind.back == statusDrawSettings && g.settingsBack == statusDrawPause {
+		background = g.images.frame
+	}
+	screen.DrawImage(background, op)
+	screen.DrawImage(g.images.overlay, op)
+
+	gametext.Draw(screen, "CONTROLS", 274, 40)
+	gametext.Draw(screen, "KEYBOARD", 105, 75)
+	gametext.Draw(screen, "GAMEPAD", 335, 75)
+	gametext.Draw(screen, g.rebind.message, 105, 375)
+	g.controlsButtons.Render(screen)
+}
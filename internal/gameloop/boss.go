@@ -0,0 +1,277 @@
+package gameloop
+
+import (
+	"image/color"
+	"maps"
+	"slices"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/levels"
+	"spaceinvader/internal/sprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	bossId             = -1
+	bossY              = 30
+	bossDyingFrames    = 90
+	minionIdStart      = 1000
+	minionCount        = 3
+	laserWarningFrames = 45
+	laserFrames        = 120
+	laserWidth         = 14
+)
+
+type laser struct {
+	timer int
+	hit   bool
+}
+
+type boss struct {
+	config      levels.Boss
+	serviceType defaultconfig.AlibabaServiceType
+	sprite      sprite.Sprite
+	health      int
+	moveOffset  float64
+	attackTimer int
+	laser       *laser
+	dyingTimer  int
+	exploded    bool
+	explosionId int
+	explosions  map[int]sprite.Sprite
+}
+
+func (u *ufos) newBoss(config levels.Boss) *boss {
+	b := &boss{
+		config:      config,
+		serviceType: defaultconfig.BossRom,
+		health:      config.Health,
+		moveOffset:  config.Speed,
+		explosions:  map[int]sprite.Sprite{},
+	}
+
+	b.sprite = sprite.New(
+		config.Images,
+		config.Width, config.Height,
+		sprite.SpiteOptions{
+			Id:                           bossId,
+			X:                            float64(ScreenW-config.Width) / 2,
+			Y:                            bossY,
+			Animate:                      true,
+			AfterAnimationImages:         u.explosionImages,
+			AfterAnimationAnimationDelay: 3,
+			AfterAnimationCallback: func(sprite.Sprite) {
+				b.exploded = true
+			},
+		},
+	)
+
+	return b
+}
+
+// renderBoss moves the boss and runs its attacks, it returns true when the boss is destroyed
+func (u *ufos) renderBoss(screen *ebiten.Image) bool {
+	b := u.boss
+	b.sprite.Render(screen)
+	if b.exploded {
+		b.sprite.Close()
+		return true
+	}
+
+	if b.health == 0 {
+		u.renderBossDying(screen)
+		return false
+	}
+
+	if !b.sprite.MoveX(b.moveOffset) {
+		b.moveOffset = -b.moveOffset
+	}
+
+	u.renderLaser(screen)
+
+	// The next attack waits until the laser is over
+	b.attackTimer++
+	if b.attackTimer >= b.config.AttackDelay && b.laser == nil {
+		b.attackTimer = 0
+		u.bossAttack(b.config.Attacks[u.rnd.IntN(len(b.config.Attacks))])
+	}
+
+	b.drawHealth(screen)
+
+	return false
+}
+
+// bossAlive reports if the boss can still be hit, while dying it only explodes
+func (u *ufos) bossAlive() bool {
+	return u.boss != nil && u.boss.health > 0
+}
+
+func (u *ufos) hitBoss() {
+	b := u.boss
+	if b.health == 0 {
+		return
+	}
+
+	b.health--
+	if b.health == 0 {
+		b.dyingTimer = bossDyingFrames
+		b.laser = nil
+	}
+}
+
+func (u *ufos) bossAttack(attack string) {
+	switch attack {
+	case levels.AttackSpread:
+		u.bossSpread()
+	case levels.AttackLaser:
+		u.boss.laser = &laser{}
+	case levels.AttackMinions:
+		u.summonMinions()
+	}
+}
+
+// bossSpread drops a fan of bombs from the bottom of the boss
+func (u *ufos) bossSpread() {
+	x, y := u.bossBottom()
+	for i := -2; i <= 2; i++ {
+		u.dropBomb(x, y, clamp(x+float64(i)*120, 0, ScreenW-20))
+	}
+}
+
+// renderLaser runs a laser attack, it is only a warning line first, then a beam sweeping with the boss
+func (u *ufos) renderLaser(screen *ebiten.Image) {
+	l := u.boss.laser
+	if l == nil {
+		return
+	}
+
+	l.timer++
+	if l.timer >= laserFrames {
+		u.boss.laser = nil
+		return
+	}
+
+	x, y := u.bossBottom()
+	firing := l.timer > laserWarningFrames
+	if hit := u.playersUnder(x, laserWidth); firing && !l.hit && len(hit) > 0 {
+		l.hit = u.hitPlayers(hit)
+	}
+
+	drawLaser(screen, x, y, laserWidth, firing)
+}
+
+// summonMinions releases small ships drifting to the ground, they cost a life if they reach the player
+func (u *ufos) summonMinions() {
+	x, y := u.bossBottom()
+	minionEnemy := u.enemies[u.boss.config.Minion]
+	for i := range minionCount {
+		offset := float64(i-minionCount/2) * 60
+		u.minionId++
+		u.types[u.minionId] = minionEnemy
+		minion := sprite.New(
+			minionEnemy.Images,
+			30, 30,
+			sprite.SpiteOptions{
+				Id:                           u.minionId,
+				X:                            clamp(x+offset-15, 0, ScreenW-30),
+				Y:                            y,
+				Animate:                      true,
+				SoftY:                        60,
+				AfterAnimationImages:         u.explosionImages,
+				AfterAnimationCallback:       u.explosionCallback,
+				AfterAnimationAnimationDelay: 2,
+				CollisionSprites:             u.players,
+				CollisionCallback: func(minion sprite.Sprite, players []sprite.Sprite) {
+					if u.hitPlayers(players) {
+						minion.RunAfterAnimation()
+					}
+				},
+			},
+		)
+		minion.Soft(true)
+		minion.SetY(ScreenH - 40)
+		u.ufoList[u.minionId] = minion
+	}
+}
+
+// renderMinions renders the summoned ships, which explode when they reach the ground
+func (u *ufos) renderMinions(screen *ebiten.Image) {
+	for _, id := range slices.Sorted(maps.Keys(u.ufoList)) {
+		if id < minionIdStart {
+			continue
+		}
+
+		minion := u.ufoList[id]
+		minion.Render(screen)
+		if !minion.IsMoving() && !minion.InAfterAnimation() {
+			minion.RunAfterAnimation()
+		}
+	}
+}
+
+// renderBossDying shows explosions all over the boss before the final one
+func (u *ufos) renderBossDying(screen *ebiten.Image) {
+	b := u.boss
+	if b.dyingTimer > 0 {
+		b.dyingTimer--
+		if b.dyingTimer%10 == 0 {
+			u.addBossExplosion()
+		}
+
+		if b.dyingTimer == 0 {
+			b.sprite.RunAfterAnimation()
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(b.explosions)) {
+		b.explosions[id].Render(screen)
+	}
+}
+
+func (u *ufos) addBossExplosion() {
+	b := u.boss
+	b.explosionId++
+	id := b.explosionId
+	explosion := sprite.New(
+		[]string{
+			"internal/images/Rocks/up00000.png",
+		},
+		100, 100,
+		sprite.SpiteOptions{
+			Id:                   id,
+			X:                    clamp(b.sprite.GetX()+u.rnd.Float64()*b.sprite.GetWidth()-50, 0, ScreenW-100),
+			Y:                    clamp(b.sprite.GetY()+u.rnd.Float64()*b.sprite.GetHeight()-28, 0, ScreenH-100),
+			AfterAnimationImages: u.explosionImages,
+			AfterAnimationCallback: func(sp sprite.Sprite) {
+				sp.Close()
+				delete(b.explosions, id)
+			},
+		},
+	)
+	explosion.RunAfterAnimation()
+	b.explosions[id] = explosion
+}
+
+func (u *ufos) bossBottom() (float64, float64) {
+	s := u.boss.sprite
+
+	return s.GetX() + s.GetWidth()/2, s.GetY() + s.GetHeight()
+}
+
+func (b *boss) drawHealth(screen *ebiten.Image) {
+	if screen == nil {
+		return
+	}
+
+	const barX, barY, barW, barH = 270, 56, 200, 10
+	gametext.Draw(screen, defaultconfig.ServiceDescriptionMap[b.serviceType], 170, 66)
+	vector.DrawFilledRect(screen, barX, barY, barW, barH, color.RGBA{80, 20, 20, 255}, false)
+	filled := barW * float32(b.health) / float32(b.config.Health)
+	vector.DrawFilledRect(screen, barX, barY, filled, barH, color.RGBA{240, 33, 33, 255}, false)
+}
+
+func clamp(value, low, high float64) float64 {
+	return max(low, min(value, high))
+}
@@ -0,0 +1,54 @@
+package gameloop
+
+import (
+	"spaceinvader/internal/controls"
+	"testing"
+)
+
+func TestDyingBossIsPaidOnce(t *testing.T) {
+	t.Chdir("../..")
+	s := NewSimulation(SimulationOptions{Seed: 1})
+	g := s.game
+	// The robot stays under the boss and keeps firing, also while it is dying
+	s.script = func(tick int, state State) []controls.Action {
+		actions := []controls.Action{}
+		if tick%2 == 0 {
+			actions = append(actions, controls.Fire)
+		}
+
+		b := g.ufos.boss.sprite
+		if target := b.GetX() + b.GetWidth()/2; state.Player.X+g.players[0].sprite.GetWidth()/2 < target {
+			actions = append(actions, controls.Right)
+		} else {
+			actions = append(actions, controls.Left)
+		}
+
+		return actions
+	}
+
+	headless(func() {
+		for g.currentLevel().Boss == nil {
+			g.level++
+		}
+		clear(g.ufos.ufoList)
+		g.ufos.init(g.currentLevel())
+	})
+	// The next shot kills it
+	g.ufos.boss.health = 1
+	for g.ufos.bossAlive() {
+		if s.State().Ended() {
+			t.Fatalf("the boss was not killed, state %+v", s.State())
+		}
+		s.Step()
+	}
+
+	// Only the boss is left to shoot at
+	clear(g.ufos.ufoList)
+	score := s.State().Score
+	for range bossDyingFrames {
+		s.Step()
+		if s.State().Score != score {
+			t.Fatalf("the dying boss paid again, score %d after %d", s.State().Score, score)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package gameloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"maps"
+	"slices"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/hud"
+	"spaceinvader/internal/storage"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const codexKey = "codex"
+
+// codex keeps the cloud services the player has met, a power-up is unlocked when caught, the boss when destroyed
+type codex struct {
+	// storage is nil when nothing is persisted, as in the simulation
+	storage  storage.Storage
+	unlocked map[defaultconfig.AlibabaServiceType]bool
+}
+
+func loadCodex(store storage.Storage) codex {
+	c := codex{
+		storage:  store,
+		unlocked: map[defaultconfig.AlibabaServiceType]bool{},
+	}
+	if store == nil {
+		return c
+	}
+
+	data, err := store.Load(codexKey)
+	if err != nil || data == nil {
+		if err != nil {
+			fmt.Println(err)
+		}
+		return c
+	}
+
+	var unlocked []defaultconfig.AlibabaServiceType
+	if err := json.Unmarshal(data, &unlocked); err != nil {
+		fmt.Println("cannot decode codex:", err)
+		return c
+	}
+
+	for _, serviceType := range unlocked {
+		c.unlocked[serviceType] = true
+	}
+
+	return c
+}
+
+func (c *codex) unlock(serviceType defaultconfig.AlibabaServiceType) {
+	if c.unlocked[serviceType] {
+		return
+	}
+
+	c.unlocked[serviceType] = true
+	if c.storage == nil {
+		return
+	}
+
+	data, err := json.Marshal(slices.Sorted(maps.Keys(c.unlocked)))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := c.storage.Save(codexKey, data); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (g *game) initiateCodexButtons() {
+	g.openScreenButtons.New("Service codex", 475, 440, 130, 28, func() {
+		g.drawStatus = statusDrawCodex
+	})
+
+	g.codexButtons = button.New()
+	g.codexButtons.New("Back", 290, 430, 60, 28, func() {
+		g.drawStatus = statusDrawIntro
+	})
+}
+
+func (g *game) drawCodex(screen *ebiten.Image) {
+	serviceTypes := slices.Sorted(maps.Keys(defaultconfig.ServiceDescriptionMap))
+	gametext.Draw(screen, fmt.Sprintf("SERVICE CODEX - %d/%d", len(g.codex.unlocked), len(serviceTypes)), 200, 50)
+
+	locked := color.RGBA{120, 120, 120, 255}
+	for i, serviceType := range serviceTypes {
+		y := float64(100 + i*35)
+		p, isPowerUp := powerUps[serviceType]
+
+		if !g.codex.unlocked[serviceType] {
+			hint := "??? - catch it to unlock"
+			if !isPowerUp {
+				hint = "??? - destroy it to unlock"
+			}
+			gametext.DrawWithColor(screen, hint, 100, y, locked)
+			continue
+		}
+
+		gametext.Draw(screen, defaultconfig.ServiceDescriptionMap[serviceType], 100, y)
+		if !isPowerUp {
+			gametext.Draw(screen, "the boss", 420, y)
+			continue
+		}
+
+		hud.DrawLabel(screen, p.label, p.color, 40, y-16)
+		gametext.Draw(screen, p.effect, 420, y)
+	}
+
+	g.codexButtons.Render(screen)
+}
@@ -0,0 +1,149 @@
+package gameloop
+
+import (
+	"fmt"
+	"slices"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/difficulty"
+	"spaceinvader/internal/gametext"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const endlessCategory = "endless"
+
+var (
+	customLives       = []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	customMultipliers = []float64{0.5, 0.75, 1, 1.25, 1.5, 2}
+	// customInvulnerability is in frames, a second each
+	customInvulnerability = []int{60, 120, 180, 240}
+	// customCooldowns are in frames
+	customCooldowns  = []int{6, 9, 12, 16, 20}
+	customMaxBullets = []int{2, 4, 6, 8, 10}
+)
+
+func (g *game) initiateDifficultyButtons() {
+	g.addCycleButton(g.openScreenButtons, 185, 400, 130, g.difficultyCaption, g.nextDifficulty)
+	g.openScreenButtons.New("Edit custom", 185, 440, 130, 28, func() {
+		g.drawStatus = statusDrawCustom
+	})
+
+	custom := &g.customDifficulty
+	g.customButtons = button.New()
+	g.addCycleButton(g.customButtons, 220, 130, 200, func() string {
+		return "Lives: " + strconv.Itoa(custom.Lives)
+	}, func() {
+		custom.Lives = nextValue(customLives, custom.Lives)
+	})
+	g.addCustomMultiplierButton(170, "Speed", &custom.Speed)
+	g.addCustomMultiplierButton(210, "Speed up", &custom.SpeedUp)
+	g.addCustomMultiplierButton(250, "Bomb rate", &custom.BombRate)
+	g.addCustomMultiplierButton(290, "Bomb speed", &custom.BombSpeed)
+	g.addCycleButton(g.customButtons, 220, 330, 200, func() string {
+		return "Respawn shield: " + strconv.Itoa(custom.Invulnerability/60) + "s"
+	}, func() {
+		custom.Invulnerability = nextValue(customInvulnerability, custom.Invulnerability)
+	})
+	g.addCycleButton(g.customButtons, 220, 370, 200, func() string {
+		return "Fire cooldown: " + strconv.Itoa(custom.Cooldown)
+	}, func() {
+		custom.Cooldown = nextValue(customCooldowns, custom.Cooldown)
+	})
+	g.addCycleButton(g.customButtons, 220, 410, 200, func() string {
+		return "Max bullets: " + strconv.Itoa(custom.MaxBullets)
+	}, func() {
+		custom.MaxBullets = nextValue(customMaxBullets, custom.MaxBullets)
+	})
+	g.customButtons.New("Done", 220, 450, 200, 28, func() {
+		// Editing the custom difficulty selects it
+		oldCaption := g.difficultyCaption()
+		g.difficulty = g.customDifficulty
+		g.openScreenButtons.Rename(oldCaption, g.difficultyCaption())
+		g.drawStatus = statusDrawIntro
+	})
+}
+
+func (g *game) addCustomMultiplierButton(y int, name string, value *float64) {
+	g.addCycleButton(g.customButtons, 220, y, 200, func() string {
+		return name + ": x" + strconv.FormatFloat(*value, 'g', -1, 64)
+	}, func() {
+		*value = nextValue(customMultipliers, *value)
+	})
+}
+
+// difficulties returns the presets and the custom difficulty, this is also the order of the leaderboards
+func (g *game) difficulties() []difficulty.Difficulty {
+	return append(difficulty.Presets(), g.customDifficulty)
+}
+
+func (g *game) nextDifficulty() {
+	list := g.difficulties()
+	i := slices.IndexFunc(list, func(d difficulty.Difficulty) bool {
+		return d.Name == g.difficulty.Name
+	})
+	g.difficulty = list[(i+1)%len(list)]
+}
+
+func (g *game) difficultyCaption() string {
+	return "Mode: " + capitalize(g.difficulty.Name)
+}
+
+// scoreCategory is the leaderboard of the game, co-op games and endless runs have their own for each difficulty
+func (g *game) scoreCategory() string {
+	if g.playerCount() == 2 {
+		return coopCategory + "-" + difficultyCategory(g.difficulty)
+	}
+
+	if g.isEndless() {
+		return endlessCategory + "-" + difficultyCategory(g.difficulty)
+	}
+
+	return difficultyCategory(g.difficulty)
+}
+
+// difficultyCategory names the leaderboard of a difficulty, the custom games are ranked with the same settings only
+func difficultyCategory(d difficulty.Difficulty) string {
+	if d.Name != difficulty.Custom.Name {
+		return d.Name
+	}
+
+	return fmt.Sprintf("%s-%d-%g-%g-%g-%g-%d-%d-%d", d.Name, d.Lives, d.Speed, d.SpeedUp, d.BombRate, d.BombSpeed,
+		d.Invulnerability, d.Cooldown, d.MaxBullets)
+}
+
+func (g *game) nextScoresCategory() {
+	categories := []string{}
+	for _, prefix := range []string{"", endlessCategory + "-", coopCategory + "-"} {
+		for _, d := range g.difficulties() {
+			categories = append(categories, prefix+difficultyCategory(d))
+		}
+	}
+
+	g.scoresCategory = nextValue(categories, g.scoresCategory)
+	g.userScores = nil
+}
+
+func (g *game) drawCustomDifficulty(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(g.images.titleImage, op)
+	screen.DrawImage(g.images.overlay, op)
+	gametext.Draw(screen, "CUSTOM DIFFICULTY", 235, 100)
+	g.customButtons.Render(screen)
+}
+
+// nextValue returns the value after current, wrapping around, or the first one if current is not in the list
+func nextValue[T comparable](values []T, current T) T {
+	i := slices.Index(values, current)
+
+	return values[(i+1)%len(values)]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,53 @@
+package gameloop
+
+import (
+	"image/color"
+	"spaceinvader/internal/gametext"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const floatingTextFrames = 60
+
+// floatingText is a short message rising from a position of the playfield, as the points of a hit
+type floatingText struct {
+	text  string
+	x, y  float64
+	timer int
+	color color.RGBA
+}
+
+func (g *game) addFloatingText(text string, x, y float64, textColor color.RGBA) {
+	g.floatingTexts = append(g.floatingTexts, &floatingText{
+		text:  text,
+		x:     x,
+		y:     y,
+		timer: floatingTextFrames,
+		color: textColor,
+	})
+}
+
+func (g *game) renderFloatingTexts(screen *ebiten.Image) {
+	texts := g.floatingTexts[:0]
+	for _, t := range g.floatingTexts {
+		t.timer--
+		t.y -= 0.5
+		if t.timer <= 0 {
+			continue
+		}
+
+		if screen != nil {
+			// The colors are premultiplied, so all the channels fade out
+			fade := min(1, float64(t.timer)/(floatingTextFrames/2))
+			textColor := color.RGBA{
+				R: uint8(float64(t.color.R) * fade),
+				G: uint8(float64(t.color.G) * fade),
+				B: uint8(float64(t.color.B) * fade),
+				A: uint8(float64(t.color.A) * fade),
+			}
+			gametext.DrawWithColor(screen, t.text, t.x, t.y, textColor)
+		}
+		texts = append(texts, t)
+	}
+	g.floatingTexts = texts
+}
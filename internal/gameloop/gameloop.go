@@ -3,13 +3,25 @@ package gameloop
 import (
 	"bytes"
 	"fmt"
+	"image/color"
 	"io"
+	"maps"
+	"math/rand/v2"
+	"slices"
 	"spaceinvader/internal/api"
 	"spaceinvader/internal/button"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/difficulty"
 	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/hud"
 	"spaceinvader/internal/inputbox"
+	"spaceinvader/internal/levels"
+	"spaceinvader/internal/live"
 	"spaceinvader/internal/sprite"
+	"spaceinvader/internal/storage"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -23,6 +35,9 @@ const (
 	ScreenW    = 640
 	ScreenH    = 480
 	sampleRate = 44100
+	// wonFrames is how long the won image is shown before the endless waves start
+	wonFrames  = 180
+	levelsPath = "internal/levels/levels.json"
 )
 
 const (
@@ -30,6 +45,16 @@ const (
 	statusDrawGame
 	statusDrawInputScore
 	statusDrawTop10
+	statusDrawPause
+	statusDrawSettings
+	statusDrawCustom
+	statusDrawCodex
+	statusDrawOnline
+	statusDrawWatch
+	statusDrawWatching
+	statusDrawControls
+	statusDrawResume
+	statusDrawAchievements
 )
 
 type drawStatus int
@@ -41,41 +66,66 @@ type Game interface {
 }
 
 type images struct {
-	bg         *ebiten.Image
+	bgs        map[string]*ebiten.Image
 	wonImage   *ebiten.Image
 	lostImage  *ebiten.Image
 	titleImage *ebiten.Image
+	frame      *ebiten.Image
+	overlay    *ebiten.Image
 }
 
 type sounds struct {
 	launchSndData []byte
 	explosionSnd  *audio.Player
 	bgMusic       *audio.Player
+	mothership    *audio.Player
 }
 
 type gameStatus struct {
-	gameOver bool
-	loose    bool
-	bulletId int
-	lives    int
+	wonTimer   int
+	loose      bool
+	bulletId   int
+	shakeTimer int
 }
 
 type keyboardStatuses struct {
-	spaceDown bool
+	pauseDown bool
+}
+
+type deviceMessage struct {
+	text  string
+	timer int
 }
 
 type sprites struct {
-	playerSprite sprite.Sprite
-	bullets      map[int]sprite.Sprite
+	// players are the robots, one per player
+	players []sprite.Sprite
 }
 
 type game struct {
 	api               api.APIClient
+	storage           storage.Storage
+	bindings          *controls.Bindings
+	rebind            rebind
+	controls          controls.Controls
+	secondControls    controls.Controls
+	devices           controls.Devices
+	menuInput         menuInput
+	deviceMessage     deviceMessage
+	rnd               *rand.Rand
 	drawStatus        drawStatus
 	audioContext      *audio.Context
 	openScreenButtons button.Button
 	winButtons        button.Button
 	backButtons       button.Button
+	pauseButtons      button.Button
+	settingsButtons   button.Button
+	customButtons     button.Button
+	codexButtons      button.Button
+	onlineButtons     button.Button
+	watchingButtons   button.Button
+	controlsButtons   button.Button
+	achieveButtons    button.Button
 	inputBox          inputbox.InputBox
 	sprites           sprites
 	ufos              *ufos
@@ -84,62 +134,155 @@ type game struct {
 	score             int
 	keyboardStatuses  keyboardStatuses
 	gameStatus        gameStatus
+	settings          settings
+	settingsBack      drawStatus
+	powerUps          powerUpStatus
+	codex             codex
+	achievements      *achievements
+	best              *bestScores
+	hud               hud.HUD
+	floatingTexts     []*floatingText
+	scoring           scoring
+	coop              bool
+	online            *online
+	watch             *watch
+	broadcast         live.Broadcaster
+	run               *savedRun
+	savedRun          *savedRun
+	continueShown     bool
+	resuming          *resuming
+	players           []*player
 	level             int
+	levels            levels.Levels
+	endlessLevel      levels.Level
+	difficulty        difficulty.Difficulty
+	customDifficulty  difficulty.Difficulty
+	scoresCategory    string
 	userScores        api.UserScores
 }
 
 func New() Game {
+	store := storage.New()
+	bindings := loadBindings(store)
+	saved := loadSettings(store)
 	g := &game{
-		audioContext: audio.NewContext(sampleRate),
-		inputBox:     inputbox.New(),
-		api:          api.New(),
-		level:        0,
+		audioContext:   audio.NewContext(sampleRate),
+		inputBox:       inputbox.New(),
+		api:            api.New(),
+		storage:        store,
+		bindings:       bindings,
+		controls:       controls.New(bindings),
+		secondControls: controls.NewSecond(bindings),
+		devices:        controls.NewDevices(),
+		menuInput: menuInput{
+			down:        map[controls.Action]bool{},
+			justPressed: map[controls.Action]bool{},
+		},
+		rnd:              newRand(uint64(time.Now().UnixNano())),
+		settings:         saved,
+		achievements:     loadAchievements(store),
+		difficulty:       saved.defaultDifficulty(),
+		customDifficulty: difficulty.Custom,
 	}
 
 	g.preInit()
+	g.savedRun = g.loadRun()
+	g.applySettings()
 	g.init()
 	return g
 }
 
+func newRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
 func (g *game) preInit() {
+	g.loadLevels()
+	g.codex = loadCodex(g.storage)
+	g.best = loadBestScores(g.storage)
 	g.loadSprites()
 	g.loadImages()
 	g.loadSounds()
 	g.initiateButtons()
+	g.hud = hud.New()
 
 }
 
 func (g *game) init() {
-	g.gameStatus.lives = 3
 	g.score = 0
-	g.ufos = newUfoList(g.sprites.playerSprite, g.explosionCallback, g.hitCallback)
-	g.sprites.playerSprite.SetY(ScreenH - 50)
-	g.gameStatus.gameOver = false
+	g.level = 1
+	g.ufos = newUfoList(g.rnd, g.levels.Enemies, g.explosionCallback, g.hitCallback)
+	g.newPlayers()
+	g.ufos.init(g.currentLevel())
+	g.powerUps = newPowerUpStatus()
+	g.floatingTexts = nil
+	g.scoring = scoring{}
+	g.gameStatus.wonTimer = 0
 	g.gameStatus.loose = false
+	g.gameStatus.shakeTimer = 0
+	g.fetchGlobalBest()
 }
 
 func (g *game) Update() error {
+	g.updateMothershipSound()
+	g.updateDevices()
+
 	switch g.drawStatus {
 	case statusDrawIntro:
-		g.openScreenButtons.Update()
+		g.updateContinueButton()
+		g.updateButtons(g.openScreenButtons)
 	case statusDrawInputScore:
 		g.inputBox.Update()
-		g.winButtons.Update()
+		g.updateButtons(g.winButtons)
 	case statusDrawTop10:
 		if g.userScores == nil {
-			scores, err := g.api.Top10()
+			scores, err := g.api.Top10(g.scoresCategory)
 			if err != nil {
-				return fmt.Errorf("Cannot load from API" + err.Error())
+				return fmt.Errorf("Cannot load from API: %w", err)
 			}
 			g.userScores = scores
 		}
-		g.backButtons.Update()
+		g.updateButtons(g.backButtons)
+	case statusDrawPause:
+		g.updateButtons(g.pauseButtons)
+		if g.pauseJustPressed() {
+			g.resume()
+		}
+	case statusDrawSettings:
+		g.handleFullScreen()
+		g.updateButtons(g.settingsButtons)
+	case statusDrawCustom:
+		g.updateButtons(g.customButtons)
+	case statusDrawCodex:
+		g.updateButtons(g.codexButtons)
+	case statusDrawOnline:
+		g.updateButtons(g.onlineButtons)
+		g.checkJoined()
+	case statusDrawWatch:
+		g.updateWatch()
+	case statusDrawWatching:
+		g.updateButtons(g.watchingButtons)
+	case statusDrawControls:
+		g.updateControls()
+	case statusDrawResume:
+		// The saved run is played in Draw
+	case statusDrawAchievements:
+		g.updateButtons(g.achieveButtons)
 	default:
 		g.playBgMusic()
 		if g.handleGameOver() {
 			return nil
 		}
+		if g.handlePause() {
+			return nil
+		}
 		g.handleFullScreen()
+		if g.online != nil {
+			g.stepOnline()
+			return nil
+		}
+		g.recordInput()
+		g.autosaveRun()
 		g.handleShoot()
 		g.handleNavigation()
 
@@ -149,13 +292,20 @@ func (g *game) Update() error {
 }
 
 func (g *game) Draw(screen *ebiten.Image) {
+	// The gamepad messages and the achievements unlocked are shown over every screen
+	defer g.drawDeviceMessage(screen)
+	defer g.drawAchievementToast(screen)
+
 	op := &ebiten.DrawImageOptions{}
 	switch g.drawStatus {
 	case statusDrawIntro:
 		screen.DrawImage(g.images.titleImage, op)
 		g.openScreenButtons.Render(screen)
+		g.drawPrompts(screen, 10, 10)
 	case statusDrawTop10:
-		gametext.Draw(screen, "TOP 10", 250, 50)
+		// Centered, the custom leaderboards have long names
+		title := "TOP 10 - " + strings.ToUpper(g.scoresCategory)
+		gametext.Draw(screen, title, (ScreenW-gametext.Width(title))/2, 50)
 		for i, score := range g.userScores {
 			dateStr := score.CreatedAt
 			dt1, err := time.Parse(time.RFC3339, score.CreatedAt)
@@ -168,10 +318,33 @@ func (g *game) Draw(screen *ebiten.Image) {
 
 		}
 		g.backButtons.Render(screen)
+	case statusDrawPause:
+		g.drawPause(screen)
+	case statusDrawSettings:
+		g.drawSettings(screen)
+	case statusDrawCustom:
+		g.drawCustomDifficulty(screen)
+	case statusDrawCodex:
+		g.drawCodex(screen)
+	case statusDrawOnline:
+		g.drawOnline(screen)
+	case statusDrawWatch:
+		g.drawWatch(screen)
+	case statusDrawWatching:
+		g.drawWatching(screen)
+	case statusDrawControls:
+		g.drawControls(screen)
+	case statusDrawResume:
+		g.drawResume(screen)
+	case statusDrawAchievements:
+		g.drawAchievements(screen)
 	case statusDrawInputScore:
 		winnerText := []string{"You can now enter your name"}
 
 		winnerText = append(winnerText, "Your score is "+strconv.Itoa(g.score))
+		if g.playerCount() == 2 {
+			winnerText = append(winnerText, "Scores: "+g.playerScores())
+		}
 		for i, line := range winnerText {
 			gametext.Draw(screen, line, 60, float64(60+i*25))
 		}
@@ -180,45 +353,131 @@ func (g *game) Draw(screen *ebiten.Image) {
 		g.winButtons.Render(screen)
 
 	default:
-		if g.gameStatus.gameOver {
-			screen.DrawImage(g.images.wonImage, op)
-			return
-		}
-
 		if g.gameStatus.loose {
 			screen.DrawImage(g.images.lostImage, op)
 			return
 		}
 
-		screen.DrawImage(g.images.bg, op)
-		g.sprites.playerSprite.Render(screen)
-		g.drawBullets(screen)
+		if g.online != nil && !g.online.stepped {
+			g.drawOnlineWait(screen)
+			return
+		}
 
-		won, loose := g.ufos.render(screen)
-		if won {
-			if g.level == 4 {
-				g.gameStatus.gameOver = true
-				g.level = 1
-				return
-			}
-			g.level++
-			g.ufos.init()
+		g.renderFrame()
+		if g.online != nil {
+			g.online.stepped = false
+		} else {
+			g.recordEvent(eventDraw)
 		}
 
-		if loose {
-			g.gameStatus.loose = true
+		op.GeoM.Translate(g.shakeOffset())
+		screen.DrawImage(g.images.frame, op)
+		g.drawTouchPads(screen)
+	}
+}
+
+// renderFrame plays a frame and renders it with the HUD.
+// The frame is kept, so the pause screen can show the game behind the menu
+func (g *game) renderFrame() {
+	frame := g.images.frame
+	frame.Clear()
+	frame.DrawImage(g.images.bgs[g.currentLevel().Background], &ebiten.DrawImageOptions{})
+	if g.drawPlayfield(frame) {
+		g.hud.Draw(frame, g.hudStatus())
+		g.drawPowerUpMessage(frame)
+	}
+}
+
+// drawPlayfield moves and renders the player, bullets and ufos, screen is nil when running headless.
+// It returns false if the game is not in play, as it has just ended or the won image is shown
+func (g *game) drawPlayfield(screen *ebiten.Image) bool {
+	if g.gameStatus.wonTimer > 0 {
+		g.gameStatus.wonTimer--
+		if screen != nil {
+			screen.DrawImage(g.images.wonImage, &ebiten.DrawImageOptions{})
 		}
+		g.renderFloatingTexts(screen)
 
-		gametext.Draw(screen, "Lives: "+strconv.Itoa(g.gameStatus.lives)+" Level: "+strconv.Itoa(g.level), 20, 30)
+		return false
+	}
+
+	for _, p := range g.players {
+		g.renderPlayer(p, screen)
+	}
+	g.drawBullets(screen)
+
+	won, loose := g.ufos.render(screen)
+	g.renderPowerUps(screen)
+	g.renderFloatingTexts(screen)
+	if won {
+		g.nextLevel()
+	}
+
+	if loose {
+		g.gameStatus.loose = true
+	}
+
+	return true
+}
+
+// nextLevel starts the next level, after the last one the endless waves are generated
+func (g *game) nextLevel() {
+	g.levelCleared()
+	if g.level == len(g.levels.Levels) {
+		g.gameStatus.wonTimer = wonFrames
+	}
+
+	g.level++
+	if g.level == len(g.levels.Levels)+1 {
+		// The endless waves have their own leaderboard
+		g.fetchGlobalBest()
+	}
+	if g.isEndless() {
+		g.endlessLevel = g.levels.Endless(g.level-len(g.levels.Levels), g.rnd)
 	}
+	g.ufos.init(g.currentLevel())
+	g.achieve(achievementLevelStarted)
+}
+
+func (g *game) hudStatus() hud.Status {
+	return hud.Status{
+		Score:      g.score,
+		Multiplier: g.chainMultiplier(),
+		LocalBest:  g.best.local[g.scoreCategory()],
+		GlobalBest: g.best.globalBest(),
+		Level:      g.levelCaption(),
+		PowerUps:   g.hudPowerUps(),
+		Progress:   g.ufos.progress(),
+		Players:    g.hudPlayers(),
+	}
+}
+
+func (g *game) isEndless() bool {
+	return g.level > len(g.levels.Levels)
+}
+
+func (g *game) levelCaption() string {
+	if g.isEndless() {
+		return "Endless wave: " + strconv.Itoa(g.level-len(g.levels.Levels))
+	}
+
+	return "Level: " + strconv.Itoa(g.level)
 }
 
 func (g *game) drawBullets(screen *ebiten.Image) {
-	for _, bullet := range g.sprites.bullets {
-		bullet.Render(screen)
-		if bullet.IsMoving() == false {
-			bullet.Close()
-			delete(g.sprites.bullets, bullet.Id())
+	for _, p := range g.players {
+		// Sorted, so collisions are resolved in the same order on every run
+		for _, id := range slices.Sorted(maps.Keys(p.bullets)) {
+			bullet := p.bullets[id]
+			bullet.Render(screen)
+			// The bullet is gone if it has just hit a ufo
+			if p.bullets[id] == nil {
+				continue
+			}
+
+			if bullet.IsMoving() == false || g.ufos.hitShield(bullet, true) {
+				g.removeBullet(p, bullet)
+			}
 		}
 	}
 }
@@ -228,7 +487,13 @@ func (g *game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 }
 
 func (g *game) handleGameOver() bool {
-	if g.gameStatus.gameOver || g.gameStatus.loose {
+	if g.gameStatus.loose {
+		g.stopBroadcast()
+		g.endRun()
+		if g.online != nil {
+			g.online.session.Close()
+		}
+		g.saveLocalBest()
 		g.drawStatus = statusDrawInputScore
 		// if ebiten.IsKeyPressed(ebiten.KeyEnter) {
 		// 	g.drawStatus = statusDrawInputScore
@@ -240,110 +505,259 @@ func (g *game) handleGameOver() bool {
 	return false
 }
 
-func (*game) handleFullScreen() {
+func (g *game) handleFullScreen() {
 	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
-		ebiten.SetFullscreen(!ebiten.IsFullscreen())
-		if !ebiten.IsFullscreen() {
-			ebiten.SetWindowSize(ScreenW, ScreenH)
-		}
+		g.toggleFullScreen()
 	}
 }
 
+// handleShoot fires on every press, the rapid and auto fire power-ups keep firing in intervals
 func (g *game) handleShoot() {
-	if ebiten.IsKeyPressed(ebiten.KeySpace) {
-		if !g.keyboardStatuses.spaceDown {
-			g.playLaunchSound()
-			g.gameStatus.bulletId++
-			collisionSprites := []sprite.Sprite{}
-			for _, cSprite := range g.ufos.ufoList {
-				if cSprite != nil {
-					collisionSprites = append(collisionSprites, cSprite)
-				}
-			}
-			bullet := sprite.New(
-				[]string{
-					"internal/images/Objects/star3.png",
-				},
-				20, 20,
-				sprite.SpiteOptions{
-					Id:                g.gameStatus.bulletId,
-					AnimateOnMove:     true,
-					SoftY:             50,
-					CollisionSprites:  collisionSprites,
-					CollisionCallback: g.handleCollision,
-				},
-			)
-
-			bullet.SetX(g.sprites.playerSprite.GetX() + 15)
-			bullet.SetY(g.sprites.playerSprite.GetY() - 5)
-			bullet.Soft(true)
-			bullet.SetY(0)
-
-			g.sprites.bullets[g.gameStatus.bulletId] = bullet
+	for _, p := range g.players {
+		if p.lives > 0 && !p.respawning() {
+			g.handlePlayerShoot(p)
 		}
-		g.keyboardStatuses.spaceDown = true
-	} else {
-		g.keyboardStatuses.spaceDown = false
 	}
 }
 
-func (g *game) handleNavigation() {
-	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
-		g.sprites.playerSprite.MoveX(5)
+func (g *game) handlePlayerShoot(p *player) {
+	pressed := p.controls.Pressed(controls.Fire)
+	p.fireTimer = max(p.fireTimer-1, 0)
+	p.weapon.cooldown = max(p.weapon.cooldown-1, 0)
+	repeat := p.fireTimer == 0 &&
+		(pressed && g.powerUpActive(defaultconfig.Cdn) || g.powerUpActive(defaultconfig.ServerlessComputing))
+	if (pressed && !p.fireDown || repeat) && g.weaponReady(p) {
+		g.fire(p)
+	}
+	g.chargeWeapon(p, pressed)
+	p.fireDown = pressed
+}
+
+func (g *game) fire(p *player) {
+	g.playLaunchSound()
+	p.fireTimer = autoFireFrames
+	p.weapon.cooldown = g.difficulty.Cooldown
+	if g.powerUpActive(defaultconfig.Cdn) {
+		p.fireTimer = rapidFireFrames
+		p.weapon.cooldown = min(p.weapon.cooldown, rapidFireFrames)
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
-		g.sprites.playerSprite.MoveX(-5)
+	collisionSprites := g.bulletTargets()
+	for _, offsetX := range g.volley(p) {
+		g.addBullet(p, collisionSprites, offsetX)
 	}
 }
 
-func (g *game) handleCollision(thisSprite sprite.Sprite, collidedSprites []sprite.Sprite) {
-	if g.sprites.bullets[thisSprite.Id()] != nil {
-		g.sprites.bullets[thisSprite.Id()].Close()
-		delete(g.sprites.bullets, thisSprite.Id())
+// bulletTargets are the sprites a bullet fired now can hit
+func (g *game) bulletTargets() []sprite.Sprite {
+	collisionSprites := []sprite.Sprite{}
+	// In the order of the ids, the first one hit gets the shot and the replays must agree on it
+	for _, id := range slices.Sorted(maps.Keys(g.ufos.ufoList)) {
+		if cSprite := g.ufos.ufoList[id]; cSprite != nil {
+			collisionSprites = append(collisionSprites, cSprite)
+		}
+	}
+	if g.ufos.bossAlive() {
+		collisionSprites = append(collisionSprites, g.ufos.boss.sprite)
+	}
+	if g.ufos.mothershipFlying() {
+		collisionSprites = append(collisionSprites, g.ufos.mothership.sprite)
 	}
 
-	for _, collidedSprite := range collidedSprites {
-		if g.ufos.ufoList[collidedSprite.Id()] != nil {
-			g.ufos.ufoList[collidedSprite.Id()].RunAfterAnimation()
+	return collisionSprites
+}
+
+// addBullet launches a bullet from the player, offsetX turns it sideways
+func (g *game) addBullet(p *player, collisionSprites []sprite.Sprite, offsetX float64) {
+	g.gameStatus.bulletId++
+	g.shotFired()
+	bullet := sprite.New(
+		[]string{
+			"internal/images/Objects/star3.png",
+		},
+		20, 20,
+		sprite.SpiteOptions{
+			Id:                g.gameStatus.bulletId,
+			AnimateOnMove:     true,
+			SoftX:             50,
+			SoftY:             50,
+			CollisionSprites:  collisionSprites,
+			CollisionCallback: g.collisionCallback(p),
+		},
+	)
+
+	x := p.sprite.GetX() + 15
+	bullet.SetX(x)
+	bullet.SetY(p.sprite.GetY() - 5)
+	bullet.Soft(true)
+	bullet.SetX(clamp(x+offsetX, 0, ScreenW-20))
+	bullet.SetY(0)
+
+	p.bullets[g.gameStatus.bulletId] = bullet
+	if p.weaponLevel().piercing {
+		p.weapon.shots[g.gameStatus.bulletId] = &shot{damage: 1, hit: map[sprite.Sprite]bool{}}
+	}
+}
+
+func (g *game) handleNavigation() {
+	speed := float64(playerSpeed)
+	if g.powerUpActive(defaultconfig.Ecs) {
+		speed = elasticSpeed
+	}
+
+	for _, p := range g.players {
+		if p.lives == 0 || p.respawning() {
+			continue
+		}
+
+		if p.controls.Pressed(controls.Right) {
+			p.sprite.MoveX(speed)
+		}
+
+		if p.controls.Pressed(controls.Left) {
+			p.sprite.MoveX(-speed)
 		}
 	}
-	g.score++
-	g.playExplosionSound()
 }
 
-func (g *game) explosionCallback(sp sprite.Sprite) {
-	g.ufos.ufoList[sp.Id()].Close()
-	delete(g.ufos.ufoList, sp.Id())
+// collisionCallback handles the hits of the bullets of the player
+func (g *game) collisionCallback(p *player) func(sprite.Sprite, []sprite.Sprite) {
+	return func(thisSprite sprite.Sprite, collidedSprites []sprite.Sprite) {
+		g.handleCollision(p, thisSprite, collidedSprites)
+	}
 }
 
-func (g *game) hitCallback(bombSprite sprite.Sprite) {
-	bombSprite.Close()
-	delete(g.ufos.bombs, bombSprite.Id())
-	g.gameStatus.lives--
-	g.sprites.playerSprite.RunAfterAnimation()
+func (g *game) handleCollision(p *player, thisSprite sprite.Sprite, collidedSprites []sprite.Sprite) {
+	piercing, targets := g.pierce(p, thisSprite, g.liveTargets(collidedSprites))
+	if len(targets) == 0 {
+		return
+	}
+
+	damage := p.shotDamage(thisSprite)
+	if !piercing {
+		if p.bullets[thisSprite.Id()] != nil {
+			p.bullets[thisSprite.Id()].Close()
+			delete(p.bullets, thisSprite.Id())
+		}
+		g.shotHit()
+	}
+
+	for _, target := range targets {
+		x, y := target.GetX(), target.GetY()
+		if g.ufos.boss != nil && target == g.ufos.boss.sprite {
+			for range damage {
+				g.ufos.hitBoss()
+			}
+			g.addPoints(p, bossHitPoints*damage, thisSprite.GetX(), thisSprite.GetY(), pointsColor)
+			if b := g.ufos.boss; b.health == 0 {
+				g.codex.unlock(b.serviceType)
+				g.achieve(achievementBossDefeated)
+				g.addPoints(p, b.config.Points, x+target.GetWidth()/2-30, y+target.GetHeight()/2, bonusColor)
+				g.weaponKill(p)
+			}
+			continue
+		}
+
+		if g.ufos.mothership != nil && target == g.ufos.mothership.sprite {
+			g.addPoints(p, g.ufos.hitMothership(), x, y+30, bonusColor)
+			g.weaponKill(p)
+			continue
+		}
+
+		if g.ufos.armourHolds(target, damage) {
+			continue
+		}
+
+		target.RunAfterAnimation()
+		g.addKillScore(p, g.ufos.types[target.Id()].Points, x, y)
+		g.achieve(achievementKill)
+		g.weaponKill(p)
+		g.dropPowerUp(x, y)
+	}
 
 	g.playExplosionSound()
-	if g.gameStatus.lives == 0 {
-		g.level = 1
-		g.gameStatus.loose = true
+}
+
+// liveTargets drops the exploding and already destroyed ufos, bullets fly through them
+func (g *game) liveTargets(collidedSprites []sprite.Sprite) []sprite.Sprite {
+	targets := []sprite.Sprite{}
+	for _, collidedSprite := range collidedSprites {
+		// A dying boss is gone for the bullets already in flight too, it was paid for once
+		isBoss := g.ufos.bossAlive() && collidedSprite == g.ufos.boss.sprite
+		isMothership := g.ufos.mothershipFlying() && collidedSprite == g.ufos.mothership.sprite
+		if !isBoss && !isMothership && g.ufos.ufoList[collidedSprite.Id()] != collidedSprite {
+			continue
+		}
+
+		if !collidedSprite.InAfterAnimation() {
+			targets = append(targets, collidedSprite)
+		}
 	}
+
+	return targets
+}
+
+func (g *game) explosionCallback(sp sprite.Sprite) {
+	g.ufos.ufoList[sp.Id()].Close()
+	delete(g.ufos.ufoList, sp.Id())
 }
 
 func (g *game) loadImages() {
 	titleImg, _, _ := ebitenutil.NewImageFromFile("internal/images/BGS/robotitle.png")
-	bgImg, _, _ := ebitenutil.NewImageFromFile("internal/images/BGS/sky.png")
 	wonImg, _, _ := ebitenutil.NewImageFromFile("internal/images/youwon.png")
 	lostImg, _, _ := ebitenutil.NewImageFromFile("internal/images/lost.png")
+	overlayImg := ebiten.NewImage(ScreenW, ScreenH)
+	overlayImg.Fill(color.RGBA{0, 0, 0, 160})
+
+	bgImgs := map[string]*ebiten.Image{}
+	for _, level := range g.levels.Levels {
+		if _, ok := bgImgs[level.Background]; !ok {
+			bgImgs[level.Background], _, _ = ebitenutil.NewImageFromFile(level.Background)
+		}
+	}
 
 	g.images = images{
-		bg:         bgImg,
+		bgs:        bgImgs,
 		wonImage:   wonImg,
 		lostImage:  lostImg,
 		titleImage: titleImg,
+		frame:      ebiten.NewImage(ScreenW, ScreenH),
+		overlay:    overlayImg,
 	}
 }
 
+// loadLevels reads the level file, so levels can be changed without rebuilding the game.
+// The built in levels are used if it cannot be loaded
+func (g *game) loadLevels() {
+	g.levels = levels.Default()
+
+	file, err := ebitenutil.OpenFile(levelsPath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	loaded, err := levels.Load(file)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	g.levels = loaded
+}
+
+// currentLevel returns the level being played scaled to the difficulty
+func (g *game) currentLevel() levels.Level {
+	if g.isEndless() {
+		return g.difficulty.Apply(g.endlessLevel)
+	}
+
+	return g.difficulty.Apply(g.levels.Levels[g.level-1])
+}
+
 func (g *game) loadSounds() {
 	explosionSnd, _ := g.loadMp3Sound("internal/sound/fire.mp3")
 	launchSndData, _ := g.loadMp3SoundData("internal/sound/rlauncher.mp3")
@@ -354,29 +768,46 @@ func (g *game) loadSounds() {
 		launchSndData: launchSndData,
 		bgMusic:       bgMusic,
 	}
+
+	pcm := mothershipSound()
+	g.sounds.mothership, _ = g.audioContext.NewPlayer(audio.NewInfiniteLoop(bytes.NewReader(pcm), int64(len(pcm))))
 }
 
 func (g *game) initiateButtons() {
 	g.openScreenButtons = button.New()
-	g.openScreenButtons.New("Play the game", 50, 400, 130, 28, func() {
+	g.openScreenButtons.New("Play the game", 40, 400, 130, 28, func() {
+		g.leaveOnline()
 		g.drawStatus = statusDrawGame
+		seed := uint64(time.Now().UnixNano())
+		g.rnd = newRand(seed)
 		g.init()
+		g.startBroadcast(seed)
+		g.startRun(g.gameHeader(seed))
 	})
-	g.openScreenButtons.New("Display scores", 450, 400, 135, 28, func() {
+	g.addCycleButton(g.openScreenButtons, 40, 440, 130, g.playersCaption, func() {
+		g.coop = !g.coop
+	})
+	g.openScreenButtons.New("Display scores", 475, 400, 130, 28, func() {
+		g.scoresCategory = g.scoreCategory()
 		g.drawStatus = statusDrawTop10
 	})
 
 	g.winButtons = button.New()
 	g.winButtons.New("Cancel", 50, 400, 70, 28, func() {
+		g.leaveOnline()
 		g.drawStatus = statusDrawIntro
 		g.userScores = nil
 	})
 	g.winButtons.New("Save", 500, 400, 55, 28, func() {
 		if len(g.inputBox.Text()) >= 3 {
-			err := g.api.AddScore(g.inputBox.Text(), g.score)
+			err := g.api.AddScore(g.inputBox.Text(), g.score, g.scoreCategory())
 			if err != nil {
 				fmt.Println(err)
 			}
+			if g.settings.SyncAchievements && defaultconfig.AchievementsUrl != "" {
+				g.achievements.sync(g.api, g.inputBox.Text())
+			}
+			g.leaveOnline()
 			g.drawStatus = statusDrawIntro
 			g.userScores = nil
 		}
@@ -387,13 +818,38 @@ func (g *game) initiateButtons() {
 		g.drawStatus = statusDrawIntro
 		g.userScores = nil
 	})
+
+	g.backButtons.New("Next list", 400, 400, 95, 28, g.nextScoresCategory)
+
+	g.initiateDifficultyButtons()
+	g.initiateCodexButtons()
+	g.initiatePauseButtons()
+	g.initiateSettingsButtons()
+	g.initiateControlsButtons()
+	g.initiateAchievementsButtons()
+	g.initiateOnlineButtons()
+	g.initiateWatchButtons()
+}
+
+func (g *game) playersCaption() string {
+	return "Players: " + strconv.Itoa(g.playerCount())
+}
+
+// addCycleButton adds a button which steps to the next value on click and shows it in its caption
+func (g *game) addCycleButton(buttons button.Button, x, y, w int, caption func() string, next func()) {
+	buttons.New(caption(), x, y, w, 28, func() {
+		oldCaption := caption()
+		next()
+		buttons.Rename(oldCaption, caption())
+	})
 }
 
 func (g *game) loadSprites() {
-	g.sprites = sprites{
-		playerSprite: sprite.New(
+	g.sprites = sprites{}
+	for _, image := range playerImages {
+		g.sprites.players = append(g.sprites.players, sprite.New(
 			[]string{
-				"internal/images/robot-fighter.png",
+				image,
 			},
 			50, 50,
 			sprite.SpiteOptions{
@@ -402,8 +858,7 @@ func (g *game) loadSprites() {
 				AnimateOnMove:        true,
 				AfterAnimationImages: g.ufos.getExplosionImages(),
 			},
-		),
-		bullets: map[int]sprite.Sprite{},
+		))
 	}
 }
 
@@ -432,6 +887,10 @@ func (g *game) loadMp3SoundData(path string) ([]byte, error) {
 }
 
 func (g *game) playExplosionSound() {
+	if g.sounds.explosionSnd == nil {
+		return
+	}
+
 	if !g.sounds.explosionSnd.IsPlaying() {
 		g.sounds.explosionSnd.Rewind()
 		g.sounds.explosionSnd.Play()
@@ -439,9 +898,15 @@ func (g *game) playExplosionSound() {
 }
 
 func (g *game) playLaunchSound() {
+	if g.audioContext == nil {
+		return
+	}
+
+	volume := g.settings.sfxVolume()
 	go func() {
 		audioStream, _ := mp3.DecodeWithSampleRate(sampleRate, bytes.NewReader(g.sounds.launchSndData))
 		sound, _ := g.audioContext.NewPlayer(audioStream)
+		sound.SetVolume(volume)
 
 		if !sound.IsPlaying() {
 			sound.Rewind()
@@ -451,7 +916,7 @@ func (g *game) playLaunchSound() {
 }
 
 func (g *game) playBgMusic() {
-	if g.sounds.bgMusic == nil {
+	if g.sounds.bgMusic == nil || g.settings.musicVolume() == 0 {
 		return
 	}
 
@@ -460,3 +925,19 @@ func (g *game) playBgMusic() {
 		g.sounds.bgMusic.Play()
 	}
 }
+
+// updateMothershipSound plays the warble only while the mothership is flying in a running game
+func (g *game) updateMothershipSound() {
+	if g.sounds.mothership == nil {
+		return
+	}
+
+	flying := g.drawStatus == statusDrawGame && !g.gameStatus.loose && g.ufos.mothershipFlying()
+	if flying && !g.sounds.mothership.IsPlaying() {
+		g.sounds.mothership.Play()
+	}
+
+	if !flying && g.sounds.mothership.IsPlaying() {
+		g.sounds.mothership.Pause()
+	}
+}
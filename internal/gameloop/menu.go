@@ -0,0 +1,104 @@
+package gameloop
+
+import (
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/hud"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// deviceMessageFrames is how long the gamepad connected and disconnected messages are shown
+const deviceMessageFrames = 180
+
+// menuDirections are the actions moving between the menu buttons
+var menuDirections = []struct {
+	action controls.Action
+	dx, dy int
+}{
+	{controls.Left, -1, 0},
+	{controls.Right, 1, 0},
+	{controls.Up, 0, -1},
+	{controls.Down, 0, 1},
+}
+
+// prompts are the actions shown on the intro and pause screens, with the key or button of the device used last
+var prompts = []struct {
+	action  controls.Action
+	caption string
+}{
+	{controls.Left, ""},
+	{controls.Right, "move"},
+	{controls.Fire, "fire"},
+	{controls.Pause, "pause"},
+}
+
+type menuInput struct {
+	down        map[controls.Action]bool
+	justPressed map[controls.Action]bool
+}
+
+// updateDevices follows the gamepads and the menu actions of the first player, on every screen
+func (g *game) updateDevices() {
+	g.devices.Update()
+	if message := g.devices.Message(); message != "" {
+		g.deviceMessage.text = message
+		g.deviceMessage.timer = deviceMessageFrames
+	}
+	g.deviceMessage.timer = max(g.deviceMessage.timer-1, 0)
+
+	actions := []controls.Action{controls.Confirm}
+	for _, d := range menuDirections {
+		actions = append(actions, d.action)
+	}
+
+	clear(g.menuInput.justPressed)
+	for _, action := range actions {
+		pressed := g.controls.Pressed(action)
+		g.menuInput.justPressed[action] = pressed && !g.menuInput.down[action]
+		g.menuInput.down[action] = pressed
+	}
+}
+
+// updateButtons handles the mouse and the touches, and the arrow keys or the gamepad moving between the buttons
+func (g *game) updateButtons(buttons button.Button) {
+	for _, d := range menuDirections {
+		// The touch pads are for the game, the buttons are tapped
+		if g.menuInput.justPressed[d.action] && g.devices.Last() != controls.Touch {
+			buttons.Navigate(d.dx, d.dy)
+		}
+	}
+
+	if g.menuInput.justPressed[controls.Confirm] {
+		buttons.Press()
+		return
+	}
+
+	buttons.Update()
+}
+
+// drawPrompts shows the controls of the first player, as keys or as gamepad buttons
+func (g *game) drawPrompts(screen *ebiten.Image, x, y float64) {
+	device := g.devices.Last()
+	for _, p := range prompts {
+		x += hud.DrawGlyph(screen, g.bindings.Glyph(device, p.action), p.caption, device == controls.Gamepad, x, y)
+	}
+}
+
+// drawTouchPads shows the touch pads over the game once the touch screen is used
+func (g *game) drawTouchPads(screen *ebiten.Image) {
+	if g.devices.Last() != controls.Touch {
+		return
+	}
+
+	for _, pad := range controls.TouchPads() {
+		hud.DrawPad(screen, pad.Caption, float64(pad.X), float64(pad.Y), float64(pad.W), float64(pad.H))
+	}
+}
+
+func (g *game) drawDeviceMessage(screen *ebiten.Image) {
+	if g.deviceMessage.timer > 0 {
+		gametext.Draw(screen, g.deviceMessage.text, 20, 150)
+	}
+}
@@ -0,0 +1,119 @@
+package gameloop
+
+import (
+	"encoding/binary"
+	"math"
+	"spaceinvader/internal/sprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	mothershipY        = 4
+	mothershipW        = 50
+	mothershipSpeed    = 2.5
+	mothershipDelayMin = 600
+	mothershipDelayMax = 1500
+)
+
+// mothershipBonuses are the points picked at random when the mothership is hit
+var mothershipBonuses = []int{50, 100, 150, 300}
+
+// mothership is the bonus ship crossing the top of the screen from time to time
+type mothership struct {
+	sprite sprite.Sprite
+	speed  float64
+	bonus  int
+	hit    bool
+}
+
+func (u *ufos) nextMothershipDelay() int {
+	return mothershipDelayMin + u.rnd.IntN(mothershipDelayMax-mothershipDelayMin)
+}
+
+// renderMothership counts down to the next crossing while a formation is in play, then moves the mothership
+func (u *ufos) renderMothership(screen *ebiten.Image) {
+	m := u.mothership
+	if m == nil {
+		if len(u.ufoList) > 0 && u.boss == nil {
+			u.mothershipTimer--
+			if u.mothershipTimer <= 0 {
+				u.launchMothership()
+			}
+		}
+
+		return
+	}
+
+	m.sprite.Render(screen)
+	if m.hit {
+		return
+	}
+
+	if !m.sprite.MoveX(m.speed) {
+		m.sprite.Close()
+		u.mothership = nil
+		u.mothershipTimer = u.nextMothershipDelay()
+	}
+}
+
+func (u *ufos) launchMothership() {
+	m := &mothership{
+		speed: mothershipSpeed,
+		bonus: mothershipBonuses[u.rnd.IntN(len(mothershipBonuses))],
+	}
+
+	x := 0.0
+	if u.rnd.IntN(2) == 0 {
+		x = ScreenW - mothershipW
+		m.speed = -m.speed
+	}
+
+	m.sprite = sprite.New(
+		[]string{
+			"internal/images/Ships/Spaceship10.png",
+		},
+		mothershipW, 40,
+		sprite.SpiteOptions{
+			X:                            x,
+			Y:                            mothershipY,
+			AfterAnimationImages:         u.explosionImages,
+			AfterAnimationAnimationDelay: 2,
+			AfterAnimationCallback: func(sp sprite.Sprite) {
+				sp.Close()
+				u.mothership = nil
+			},
+		},
+	)
+	u.mothership = m
+}
+
+// hitMothership blows up the mothership and returns its bonus
+func (u *ufos) hitMothership() int {
+	m := u.mothership
+	m.hit = true
+	m.sprite.RunAfterAnimation()
+	u.mothershipTimer = u.nextMothershipDelay()
+
+	return m.bonus
+}
+
+func (u *ufos) mothershipFlying() bool {
+	return u.mothership != nil && !u.mothership.hit
+}
+
+// mothershipSound synthesizes the warble of the mothership, a second of 16 bit stereo samples to loop
+func mothershipSound() []byte {
+	pcm := make([]byte, sampleRate*4)
+	phase := 0.0
+	for i := range sampleRate {
+		t := float64(i) / sampleRate
+		frequency := 750 + 150*math.Sin(2*math.Pi*8*t)
+		phase += 2 * math.Pi * frequency / sampleRate
+		sample := uint16(int16(0.2 * math.MaxInt16 * math.Sin(phase)))
+		binary.LittleEndian.PutUint16(pcm[4*i:], sample)
+		binary.LittleEndian.PutUint16(pcm[4*i+2:], sample)
+	}
+
+	return pcm
+}
@@ -0,0 +1,209 @@
+package gameloop
+
+import (
+	"context"
+	"fmt"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/netplay"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// onlineInputDelay is how many frames ahead the inputs are sent, so the game rarely waits for the partner
+	onlineInputDelay = 3
+	// onlineWaitFrames pass before the waiting message is shown
+	onlineWaitFrames = 30
+)
+
+// onlineActions are exchanged, the pause is not as the game can't wait for the partner
+var onlineActions = []controls.Action{controls.Left, controls.Right, controls.Fire}
+
+type joinResult struct {
+	session netplay.Session
+	err     error
+}
+
+// online is a game played with a partner through the relay. Both games simulate the same seed
+// and play the inputs of both players in lockstep, so they stay the same without sending the state
+type online struct {
+	cancel  context.CancelFunc
+	joined  chan joinResult
+	session netplay.Session
+	// message is shown on the online screen, while waiting for a partner or after the game
+	message string
+	// frame is the next frame to play
+	frame int
+	// local are the inputs of this game sent ahead, by frame
+	local map[int]netplay.Input
+	// inputs are the controls of the robots, set to the inputs of the frame
+	inputs []*scriptedControls
+	// stepped is set when a frame has been played and is waiting to be drawn
+	stepped     bool
+	waitingTime int
+}
+
+func (g *game) initiateOnlineButtons() {
+	// The games can only be paired by a relay
+	if defaultconfig.RelayUrl != "" {
+		g.openScreenButtons.New("Online co-op", 330, 400, 130, 28, g.joinOnline)
+	}
+
+	g.onlineButtons = button.New()
+	g.onlineButtons.New("Back", 290, 400, 60, 28, func() {
+		g.leaveOnline()
+		g.drawStatus = statusDrawIntro
+	})
+}
+
+// joinOnline waits in the background for a partner with the same difficulty, as both play the same game
+func (g *game) joinOnline() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &online{
+		cancel:  cancel,
+		joined:  make(chan joinResult, 1),
+		message: "Waiting for a partner to play co-op",
+		local:   map[int]netplay.Input{},
+		inputs: []*scriptedControls{
+			{pressed: map[controls.Action]bool{}},
+			{pressed: map[controls.Action]bool{}},
+		},
+	}
+	g.online = o
+	g.drawStatus = statusDrawOnline
+
+	room := "coop:" + fmt.Sprint(g.difficulty)
+	go func() {
+		session, err := netplay.Join(ctx, defaultconfig.RelayUrl, room)
+		o.joined <- joinResult{session: session, err: err}
+	}()
+}
+
+// checkJoined starts the game once the relay has paired the games
+func (g *game) checkJoined() {
+	o := g.online
+	if o == nil || o.session != nil {
+		return
+	}
+
+	select {
+	case result := <-o.joined:
+		if result.err != nil {
+			fmt.Println(result.err)
+			o.message = "Cannot reach the relay"
+			return
+		}
+
+		o.session = result.session
+		g.rnd = newRand(o.session.Seed())
+		g.drawStatus = statusDrawGame
+		g.init()
+	default:
+	}
+}
+
+// leaveOnline ends the online game, the next games are played locally
+func (g *game) leaveOnline() {
+	if g.online == nil {
+		return
+	}
+
+	g.online.cancel()
+	if g.online.session != nil {
+		g.online.session.Close()
+	}
+	g.online = nil
+	g.rnd = newRand(uint64(time.Now().UnixNano()))
+}
+
+// stepOnline plays the next frame once the input of the partner has arrived, the frame is drawn by the next Draw
+func (g *game) stepOnline() {
+	o := g.online
+	if o.stepped {
+		return
+	}
+
+	remote, ok := o.session.Input(o.frame)
+	if o.frame < onlineInputDelay {
+		// Nothing is sent for the first frames, the robots stand still
+		remote, ok = 0, true
+	}
+
+	if !ok {
+		if o.session.Err() != nil {
+			g.endOnline("The connection to the partner is lost")
+			return
+		}
+
+		o.waitingTime++
+		return
+	}
+
+	local := inputOf(g.controls)
+	if err := o.session.Send(o.frame+onlineInputDelay, local); err != nil {
+		fmt.Println(err)
+	}
+	o.local[o.frame+onlineInputDelay] = local
+
+	player := o.session.Player()
+	o.inputs[player].set(inputActions(o.local[o.frame]))
+	o.inputs[1-player].set(inputActions(remote))
+	delete(o.local, o.frame)
+
+	g.handleShoot()
+	g.handleNavigation()
+	o.frame++
+	o.stepped = true
+	o.waitingTime = 0
+}
+
+// endOnline closes the session and shows the message on the online screen
+func (g *game) endOnline(message string) {
+	g.online.session.Close()
+	g.online.message = message
+	g.drawStatus = statusDrawOnline
+}
+
+// inputOf returns the actions held down on the controls as an input
+func inputOf(c controls.Controls) netplay.Input {
+	var input netplay.Input
+	for _, action := range onlineActions {
+		if c.Pressed(action) {
+			input |= 1 << action
+		}
+	}
+
+	return input
+}
+
+func inputActions(input netplay.Input) []controls.Action {
+	actions := []controls.Action{}
+	for _, action := range onlineActions {
+		if input&(1<<action) != 0 {
+			actions = append(actions, action)
+		}
+	}
+
+	return actions
+}
+
+func (g *game) drawOnline(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(g.images.titleImage, op)
+	screen.DrawImage(g.images.overlay, op)
+	gametext.Draw(screen, "ONLINE CO-OP", 255, 100)
+	gametext.Draw(screen, g.online.message, 170, 200)
+	g.onlineButtons.Render(screen)
+}
+
+// drawOnlineWait shows the last frame again while waiting for the input of the partner
+func (g *game) drawOnlineWait(screen *ebiten.Image) {
+	screen.DrawImage(g.images.frame, &ebiten.DrawImageOptions{})
+	if g.online.waitingTime > onlineWaitFrames {
+		gametext.Draw(screen, "Waiting for the partner...", 220, 240)
+	}
+}
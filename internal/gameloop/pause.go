@@ -0,0 +1,94 @@
+package gameloop
+
+import (
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/gametext"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func (g *game) initiatePauseButtons() {
+	g.pauseButtons = button.New()
+	g.pauseButtons.New("Resume", 245, 170, 150, 28, g.resume)
+	g.pauseButtons.New("Restart level", 245, 210, 150, 28, func() {
+		g.restartLevel()
+		g.recordEvent(eventRestart)
+		g.resume()
+	})
+	g.pauseButtons.New("Settings", 245, 250, 150, 28, func() {
+		g.openSettings(statusDrawPause)
+	})
+	g.pauseButtons.New("Quit to title", 245, 290, 150, 28, func() {
+		g.stopBroadcast()
+		// The run is saved when paused, it can be continued
+		g.run = nil
+		g.drawStatus = statusDrawIntro
+	})
+}
+
+// handlePause pauses the game when the pause key is pressed or the window loses focus.
+// The online games are never paused, as the partner can't wait
+func (g *game) handlePause() bool {
+	if g.online != nil {
+		return false
+	}
+
+	if g.pauseJustPressed() || !ebiten.IsFocused() {
+		g.pause()
+		return true
+	}
+
+	return false
+}
+
+func (g *game) pauseJustPressed() bool {
+	pressed := g.controls.Pressed(controls.Pause)
+	justPressed := pressed && !g.keyboardStatuses.pauseDown
+	g.keyboardStatuses.pauseDown = pressed
+
+	return justPressed
+}
+
+// pause pauses the game and saves the run
+func (g *game) pause() {
+	g.saveRun()
+	g.drawStatus = statusDrawPause
+	if g.sounds.bgMusic != nil {
+		g.sounds.bgMusic.Pause()
+	}
+}
+
+func (g *game) resume() {
+	g.drawStatus = statusDrawGame
+	if g.sounds.bgMusic != nil && g.settings.musicVolume() > 0 {
+		g.sounds.bgMusic.Play()
+	}
+}
+
+// restartLevel clears the bullets, bombs and power-up capsules and puts the formation back to the start of the level
+func (g *game) restartLevel() {
+	for _, p := range g.players {
+		for id, bullet := range p.bullets {
+			bullet.Close()
+			delete(p.bullets, id)
+		}
+		clear(p.weapon.shots)
+	}
+
+	for id, c := range g.powerUps.capsules {
+		c.sprite.Close()
+		delete(g.powerUps.capsules, id)
+	}
+
+	g.ufos.restart()
+}
+
+func (g *game) drawPause(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(g.images.frame, op)
+	screen.DrawImage(g.images.overlay, op)
+	gametext.Draw(screen, "PAUSED", 285, 140)
+	g.pauseButtons.Render(screen)
+	g.drawPrompts(screen, 60, 340)
+}
@@ -0,0 +1,127 @@
+package gameloop
+
+import (
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/hud"
+	"spaceinvader/internal/sprite"
+	"strconv"
+	"strings"
+)
+
+// coopCategory is the leaderboard of the two player games, followed by the difficulty
+const coopCategory = "coop"
+
+// playerImages tell the robots apart, one per player
+var playerImages = []string{
+	"internal/images/robot-fighter.png",
+	"internal/images/robot-fighter2.png",
+}
+
+// player is a robot with its own controls, lives, score and weapon, the formation and the power-ups are shared
+type player struct {
+	id       int
+	sprite   sprite.Sprite
+	controls controls.Controls
+	lives    int
+	// score are the points of this player, the team score also has the level bonuses
+	score             int
+	respawnTimer      int
+	invulnerableTimer int
+	weapon            weapon
+	bullets           map[int]sprite.Sprite
+	fireDown          bool
+	// fireTimer repeats the shots of the rapid and auto fire power-ups
+	fireTimer int
+}
+
+// playerCount is two in the co-op games, local or online
+func (g *game) playerCount() int {
+	if g.coop || g.online != nil {
+		return 2
+	}
+
+	return 1
+}
+
+// newPlayers puts the robots of a new game to the ground
+func (g *game) newPlayers() {
+	playerControls := []controls.Controls{g.controls, g.secondControls}
+	if g.online != nil {
+		// Both robots play the inputs exchanged in lockstep
+		playerControls = []controls.Controls{g.online.inputs[0], g.online.inputs[1]}
+	}
+	g.players = nil
+	for i := range g.playerCount() {
+		p := &player{
+			id:       i,
+			sprite:   g.sprites.players[i],
+			controls: playerControls[i],
+			lives:    g.difficulty.Lives,
+			weapon:   newWeapon(),
+			bullets:  map[int]sprite.Sprite{},
+		}
+		g.players = append(g.players, p)
+		g.placePlayer(p)
+	}
+	g.ufos.players = g.playerSprites()
+}
+
+// playerOf returns the player of the robot sprite, nil for any other sprite
+func (g *game) playerOf(sp sprite.Sprite) *player {
+	for _, p := range g.players {
+		if p.sprite == sp {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// playerSprites returns the robots still in the game, the enemies only aim at them
+func (g *game) playerSprites() []sprite.Sprite {
+	result := []sprite.Sprite{}
+	for _, p := range g.players {
+		if p.lives > 0 {
+			result = append(result, p.sprite)
+		}
+	}
+
+	return result
+}
+
+func (g *game) totalLives() int {
+	lives := 0
+	for _, p := range g.players {
+		lives += p.lives
+	}
+
+	return lives
+}
+
+// playerScores lists the points of each player, as "1P 120, 2P 80"
+func (g *game) playerScores() string {
+	scores := []string{}
+	for _, p := range g.players {
+		scores = append(scores, strconv.Itoa(p.id+1)+"P "+strconv.Itoa(p.score))
+	}
+
+	return strings.Join(scores, ", ")
+}
+
+func (g *game) hudPlayers() []hud.Player {
+	result := []hud.Player{}
+	for _, p := range g.players {
+		result = append(result, hud.Player{
+			Name:  strconv.Itoa(p.id+1) + "P",
+			Score: p.score,
+			Lives: p.lives,
+			Weapon: hud.Weapon{
+				Name:     p.weaponLevel().name,
+				Progress: p.weaponProgress(),
+				Charge:   float64(p.weapon.charge) / chargeFrames,
+			},
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,194 @@
+package gameloop
+
+import (
+	"image/color"
+	"maps"
+	"slices"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/hud"
+	"spaceinvader/internal/sprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// powerUpChance is one in how many destroyed ufos drop a power-up
+	powerUpChance      = 8
+	powerUpFrames      = 600
+	powerUpMessageTime = 150
+	rapidFireFrames    = 8
+	autoFireFrames     = 20
+	spreadShotX        = 90
+	playerSpeed        = 5
+	elasticSpeed       = 8
+)
+
+// powerUp is the effect of a cloud service dropped by the ufos
+type powerUp struct {
+	label  string
+	effect string
+	// frames is how long the effect lasts, zero if it's applied at once
+	frames int
+	color  color.RGBA
+}
+
+var powerUps = map[defaultconfig.AlibabaServiceType]powerUp{
+	defaultconfig.Cdn:                  {"CDN", "rapid fire", powerUpFrames, color.RGBA{255, 120, 40, 255}},
+	defaultconfig.CloudBackup:          {"CB", "extra life", 0, color.RGBA{80, 220, 100, 255}},
+	defaultconfig.BlockStorage:         {"BS", "shield", powerUpFrames, color.RGBA{80, 160, 255, 255}},
+	defaultconfig.FunctionCompute:      {"FC", "spread shot", powerUpFrames, color.RGBA{200, 90, 255, 255}},
+	defaultconfig.Ecs:                  {"ECS", "faster ship", powerUpFrames, color.RGBA{255, 220, 60, 255}},
+	defaultconfig.ServerlessComputing:  {"SC", "auto fire", powerUpFrames, color.RGBA{255, 90, 140, 255}},
+	defaultconfig.ObjectStorageService: {"OSS", "bombs cleared", 0, color.RGBA{60, 220, 220, 255}},
+	defaultconfig.ApsaraDB:             {"DB", "double score", powerUpFrames, color.RGBA{240, 240, 240, 255}},
+}
+
+type capsule struct {
+	serviceType defaultconfig.AlibabaServiceType
+	sprite      sprite.Sprite
+}
+
+type powerUpStatus struct {
+	capsules     map[int]*capsule
+	capsuleId    int
+	active       map[defaultconfig.AlibabaServiceType]int
+	message      string
+	messageTimer int
+}
+
+func newPowerUpStatus() powerUpStatus {
+	return powerUpStatus{
+		capsules: map[int]*capsule{},
+		active:   map[defaultconfig.AlibabaServiceType]int{},
+	}
+}
+
+// dropPowerUp may drop a capsule of a random service where a ufo was destroyed
+func (g *game) dropPowerUp(x, y float64) {
+	if g.rnd.IntN(powerUpChance) != 0 {
+		return
+	}
+
+	types := slices.Sorted(maps.Keys(powerUps))
+	c := &capsule{serviceType: types[g.rnd.IntN(len(types))]}
+
+	g.powerUps.capsuleId++
+	c.sprite = sprite.New(
+		[]string{
+			"internal/images/Objects/xff.png",
+		},
+		20, 28,
+		sprite.SpiteOptions{
+			Id:               g.powerUps.capsuleId,
+			X:                x,
+			Y:                y,
+			SoftY:            80,
+			CollisionSprites: g.ufos.players,
+			CollisionCallback: func(sp sprite.Sprite, collided []sprite.Sprite) {
+				// The robot is gone while respawning or without lives
+				p := g.playerOf(collided[0])
+				if p == nil || p.lives == 0 || p.respawning() {
+					return
+				}
+
+				sp.Close()
+				delete(g.powerUps.capsules, sp.Id())
+				g.collectPowerUp(p, c.serviceType)
+			},
+		},
+	)
+	c.sprite.Soft(true)
+	c.sprite.SetY(ScreenH - 28)
+	g.powerUps.capsules[g.powerUps.capsuleId] = c
+}
+
+// collectPowerUp applies the power-up to the team, the extra life goes to the player who caught it
+func (g *game) collectPowerUp(collector *player, serviceType defaultconfig.AlibabaServiceType) {
+	p := powerUps[serviceType]
+	switch serviceType {
+	case defaultconfig.CloudBackup:
+		collector.lives++
+	case defaultconfig.ObjectStorageService:
+		g.ufos.clearBombs()
+	}
+
+	g.codex.unlock(serviceType)
+	if p.frames > 0 {
+		g.powerUps.active[serviceType] = p.frames
+	}
+
+	g.powerUps.message = defaultconfig.ServiceDescriptionMap[serviceType] + ": " + p.effect
+	g.powerUps.messageTimer = powerUpMessageTime
+}
+
+func (g *game) powerUpActive(serviceType defaultconfig.AlibabaServiceType) bool {
+	return g.powerUps.active[serviceType] > 0
+}
+
+// renderPowerUps moves the falling capsules and counts down the active effects
+func (g *game) renderPowerUps(screen *ebiten.Image) {
+	for serviceType, frames := range g.powerUps.active {
+		if frames <= 1 {
+			delete(g.powerUps.active, serviceType)
+			continue
+		}
+		g.powerUps.active[serviceType] = frames - 1
+	}
+
+	g.powerUps.messageTimer = max(g.powerUps.messageTimer-1, 0)
+
+	for _, id := range slices.Sorted(maps.Keys(g.powerUps.capsules)) {
+		c := g.powerUps.capsules[id]
+		c.sprite.Render(screen)
+		if !c.sprite.IsMoving() {
+			c.sprite.Close()
+			delete(g.powerUps.capsules, id)
+			continue
+		}
+
+		if screen != nil {
+			p := powerUps[c.serviceType]
+			hud.DrawLabel(screen, p.label, p.color, c.sprite.GetX()+22, c.sprite.GetY()+4)
+		}
+	}
+
+	if screen == nil || !g.powerUpActive(defaultconfig.BlockStorage) {
+		return
+	}
+
+	for _, p := range g.players {
+		if p.lives == 0 || p.respawning() {
+			continue
+		}
+
+		player := p.sprite
+		radius := float32(player.GetWidth()) * 0.6
+		cx := float32(player.GetX() + player.GetWidth()/2)
+		cy := float32(player.GetY() + player.GetHeight()/2)
+		vector.StrokeCircle(screen, cx, cy, radius, 2, powerUps[defaultconfig.BlockStorage].color, true)
+	}
+}
+
+// drawPowerUpMessage shows the service of the last pickup
+func (g *game) drawPowerUpMessage(screen *ebiten.Image) {
+	if g.powerUps.messageTimer > 0 {
+		gametext.Draw(screen, g.powerUps.message, 20, 130)
+	}
+}
+
+// hudPowerUps returns the active power-ups with their seconds left
+func (g *game) hudPowerUps() []hud.PowerUp {
+	result := []hud.PowerUp{}
+	for _, serviceType := range slices.Sorted(maps.Keys(g.powerUps.active)) {
+		p := powerUps[serviceType]
+		result = append(result, hud.PowerUp{
+			Label:   p.label,
+			Color:   p.color,
+			Seconds: g.powerUps.active[serviceType]/60 + 1,
+		})
+	}
+
+	return result
+}
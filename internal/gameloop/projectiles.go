@@ -0,0 +1,218 @@
+package gameloop
+
+import (
+	"image/color"
+	"maps"
+	"math"
+	"slices"
+	"spaceinvader/internal/levels"
+	"spaceinvader/internal/sprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// bombSpeedReference is the bomb speed of the default levels, the projectiles fly faster in the levels with faster bombs
+	bombSpeedReference = 40
+	spreadShots        = 3
+	// spreadAngle is the angle between two shots of a spread volley
+	spreadAngle = 0.35
+	// homingFrames is how long a homing missile turns towards the player, it flies straight on after
+	homingFrames = 240
+	homingTurn   = 0.03
+	// enemyLaserWarningFrames is how long the warning line shows before the beam fires
+	enemyLaserWarningFrames = 60
+	enemyLaserFrames        = 100
+	enemyLaserWidth         = 8
+)
+
+// projectileType is the look and flight of an enemy projectile
+type projectileType struct {
+	images        []string
+	width, height int
+	// speed is in pixels per frame at the reference bomb speed, the bombs fall speeding up instead
+	speed float64
+	// margin shrinks the hitbox on each side, grazing the projectile isn't a hit
+	margin float64
+}
+
+var projectileTypes = map[string]projectileType{
+	levels.ProjectileBomb:   {[]string{"internal/images/Objects/xff2.png"}, 20, 20, 0, 0},
+	levels.ProjectileAimed:  {[]string{"internal/images/Objects/plasma.png"}, 14, 14, 3.5, 2},
+	levels.ProjectileSpread: {[]string{"internal/images/Objects/pellet.png"}, 10, 10, 2.5, 1},
+	levels.ProjectileHoming: {[]string{"internal/images/Objects/seeker.png"}, 18, 18, 1.8, 4},
+}
+
+// projectile is an enemy shot, the bombs fall softly, the others fly along their angle
+type projectile struct {
+	sprite sprite.Sprite
+	kind   string
+	angle  float64
+	speed  float64
+	frames int
+}
+
+// enemyLaser is a beam fired straight down from where the ship was when it started the warning
+type enemyLaser struct {
+	x, y  float64
+	timer int
+	hit   bool
+}
+
+// shoot fires the projectile of the ufo type
+func (u *ufos) shoot(ufo sprite.Sprite) {
+	x := ufo.GetX() + ufo.GetWidth()/2
+	y := ufo.GetY() + ufo.GetHeight()
+
+	switch u.types[ufo.Id()].Projectile {
+	case levels.ProjectileAimed, levels.ProjectileHoming:
+		u.launch(u.types[ufo.Id()].Projectile, x, y, u.aim(x, y))
+	case levels.ProjectileSpread:
+		angle := u.aim(x, y)
+		for i := range spreadShots {
+			u.launch(levels.ProjectileSpread, x, y, angle+float64(i-spreadShots/2)*spreadAngle)
+		}
+	case levels.ProjectileLaser:
+		u.lasers = append(u.lasers, &enemyLaser{x: x, y: y})
+	default:
+		u.dropBomb(ufo.GetX(), ufo.GetY(), u.bombTarget(ufo))
+	}
+}
+
+// aim is the angle from x, y to the centre of the nearest robot, straight down when no robot is left
+func (u *ufos) aim(x, y float64) float64 {
+	player := u.nearestPlayer(x)
+	if player == nil {
+		return math.Pi / 2
+	}
+
+	targetY := player.GetY() + player.GetHeight()/2
+
+	return math.Atan2(targetY-y, centerX(player)-x)
+}
+
+// launch adds a projectile centred on x, y flying along the angle
+func (u *ufos) launch(kind string, x, y, angle float64) {
+	t := projectileTypes[kind]
+	u.bombId++
+	shot := sprite.New(
+		t.images,
+		t.width, t.height,
+		sprite.SpiteOptions{
+			Id:                u.bombId,
+			X:                 clamp(x-float64(t.width)/2, 0, float64(ScreenW-t.width)),
+			Y:                 clamp(y-float64(t.height)/2, 0, float64(ScreenH-t.height)),
+			CollisionSprites:  u.players,
+			CollisionCallback: u.bombCollision,
+			CollisionMargin:   t.margin,
+		},
+	)
+
+	u.bombs[u.bombId] = &projectile{
+		sprite: shot,
+		kind:   kind,
+		angle:  angle,
+		speed:  t.speed * u.level.BombSpeed / bombSpeedReference,
+	}
+}
+
+func (u *ufos) bombCollision(bombSprite sprite.Sprite, players []sprite.Sprite) {
+	if u.hitPlayers(players) {
+		bombSprite.Close()
+		delete(u.bombs, bombSprite.Id())
+	}
+}
+
+// renderProjectiles moves the projectiles, they are gone when they land, leave the screen or hit a shield
+func (u *ufos) renderProjectiles(screen *ebiten.Image) {
+	for _, id := range slices.Sorted(maps.Keys(u.bombs)) {
+		// A hit of the player clears all the bombs
+		p := u.bombs[id]
+		if p == nil {
+			continue
+		}
+
+		p.sprite.Render(screen)
+		if u.bombs[id] == nil {
+			continue
+		}
+
+		if !u.moveProjectile(p) || u.hitShield(p.sprite, false) {
+			p.sprite.Close()
+			delete(u.bombs, id)
+		}
+	}
+
+	u.renderEnemyLasers(screen)
+}
+
+// moveProjectile reports if the projectile is still flying
+func (u *ufos) moveProjectile(p *projectile) bool {
+	if p.kind == levels.ProjectileBomb {
+		return p.sprite.IsMoving()
+	}
+
+	p.frames++
+	if p.kind == levels.ProjectileHoming && p.frames < homingFrames {
+		p.angle += clamp(angleDiff(u.aim(p.sprite.GetX(), p.sprite.GetY()), p.angle), -homingTurn, homingTurn)
+	}
+
+	return p.sprite.MoveX(p.speed*math.Cos(p.angle)) && p.sprite.MoveY(p.speed*math.Sin(p.angle))
+}
+
+// angleDiff is the shortest turn from b to a
+func angleDiff(a, b float64) float64 {
+	return math.Remainder(a-b, 2*math.Pi)
+}
+
+// renderEnemyLasers runs the lasers of the ships, the beams go through the shields
+func (u *ufos) renderEnemyLasers(screen *ebiten.Image) {
+	lasers := u.lasers[:0]
+	for _, l := range u.lasers {
+		l.timer++
+		if l.timer >= enemyLaserWarningFrames+enemyLaserFrames {
+			continue
+		}
+
+		firing := l.timer > enemyLaserWarningFrames
+		if hit := u.playersUnder(l.x, enemyLaserWidth); firing && !l.hit && len(hit) > 0 {
+			l.hit = u.hitPlayers(hit)
+			// Losing a life clears the lasers too
+			if u.lasers == nil {
+				return
+			}
+		}
+
+		drawLaser(screen, l.x, l.y, enemyLaserWidth, firing)
+		lasers = append(lasers, l)
+	}
+
+	u.lasers = lasers
+}
+
+// playersUnder returns the robots a beam of the width centred on x covers
+func (u *ufos) playersUnder(x, width float64) []sprite.Sprite {
+	result := []sprite.Sprite{}
+	for _, player := range u.players {
+		if player.GetX() < x+width/2 && player.GetX()+player.GetWidth() > x-width/2 {
+			result = append(result, player)
+		}
+	}
+
+	return result
+}
+
+// drawLaser draws a beam from y to the ground, or only a thin warning line when it isn't firing yet
+func drawLaser(screen *ebiten.Image, x, y, width float64, firing bool) {
+	if screen == nil {
+		return
+	}
+
+	if firing {
+		vector.DrawFilledRect(screen, float32(x-width/2), float32(y), float32(width), float32(ScreenH-y), color.RGBA{255, 60, 60, 220}, false)
+		return
+	}
+
+	vector.DrawFilledRect(screen, float32(x-1), float32(y), 2, float32(ScreenH-y), color.RGBA{255, 60, 60, 120}, false)
+}
@@ -0,0 +1,100 @@
+package gameloop
+
+import (
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/sprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// respawnFrames is how long the player is gone after losing a life, the explosion included
+	respawnFrames = 90
+	blinkFrames   = 6
+)
+
+// hitCallback takes a life of the robot unless it is protected, it reports if the projectile is stopped.
+// The shield power-up stops the projectiles, they fly through the robot while respawning or invulnerable
+func (g *game) hitCallback(target sprite.Sprite) bool {
+	p := g.playerOf(target)
+	if p == nil || p.lives == 0 {
+		return false
+	}
+
+	if g.powerUpActive(defaultconfig.BlockStorage) {
+		return true
+	}
+
+	if p.invulnerable() {
+		return false
+	}
+
+	g.breakChain()
+
+	p.lives--
+	g.scoring.livesLost++
+	p.weaponDowngrade()
+	p.sprite.RunAfterAnimation()
+
+	g.playExplosionSound()
+	g.gameStatus.shakeTimer = shakeFrames
+	if p.lives == 0 {
+		// The game goes on while a robot is left
+		g.ufos.players = g.playerSprites()
+		g.gameStatus.loose = len(g.ufos.players) == 0
+		return true
+	}
+
+	p.respawnTimer = respawnFrames
+	g.ufos.clearBombs()
+
+	return true
+}
+
+func (p *player) respawning() bool {
+	return p.respawnTimer > 0
+}
+
+func (p *player) invulnerable() bool {
+	return p.respawning() || p.invulnerableTimer > 0
+}
+
+// renderPlayer renders the robot, after a hit it explodes and is gone for a while,
+// then it's back at its place blinking while invulnerable. Without lives only the explosion is left
+func (g *game) renderPlayer(p *player, screen *ebiten.Image) {
+	switch {
+	case p.lives == 0:
+		if p.sprite.InAfterAnimation() {
+			p.sprite.Render(screen)
+		}
+	case p.respawnTimer > 0:
+		p.respawnTimer--
+		if p.sprite.InAfterAnimation() {
+			p.sprite.Render(screen)
+		}
+
+		if p.respawnTimer == 0 {
+			g.placePlayer(p)
+			p.invulnerableTimer = g.difficulty.Invulnerability
+		}
+	case p.invulnerableTimer > 0:
+		p.invulnerableTimer--
+		// The robot still moves while it's not drawn
+		if (p.invulnerableTimer/blinkFrames)%2 == 1 {
+			screen = nil
+		}
+		p.sprite.Render(screen)
+	default:
+		p.sprite.Render(screen)
+	}
+}
+
+// placePlayer puts the robot to its place on the ground at once, without sliding there.
+// A single robot starts at the centre, two share the ground
+func (g *game) placePlayer(p *player) {
+	player := p.sprite
+	player.Soft(false)
+	player.SetX(float64(ScreenW*(p.id+1)/(g.playerCount()+1)) - player.GetWidth()/2)
+	player.SetY(ScreenH - 50)
+	player.Soft(true)
+}
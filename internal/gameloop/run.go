@@ -0,0 +1,211 @@
+package gameloop
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/levels"
+	"spaceinvader/internal/live"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	runKey = "run"
+	// autosaveFrames saves the run every ten seconds, as closing the tab doesn't pause the game
+	autosaveFrames = 600
+	// resumeEventsPerFrame are played at once while resuming, the screen is updated in between
+	resumeEventsPerFrame = 2000
+	continueCaption      = "Continue"
+	// runVersion is raised when a change to the game plays the same events differently, older runs are dropped
+	runVersion = 1
+)
+
+// savedRun is a run in progress, the game plays the same from its seed with the same events.
+// Playing the events back rebuilds the level, the score, the lives, the formation, the bombs,
+// the power-ups and the random state as they were, in the format of the spectator stream.
+// The events only replay the same with the same game and levels, so both are saved with them
+type savedRun struct {
+	Version int         `json:"version"`
+	Levels  string      `json:"levels"`
+	Header  live.Header `json:"header"`
+	Events  []byte      `json:"events"`
+	// autosave counts the frames to the next save
+	autosave int
+}
+
+// resuming plays a saved run back before the player takes over
+type resuming struct {
+	events []byte
+	played int
+	// lastDraw is rendered, the pause screen shows it
+	lastDraw int
+	inputs   []*scriptedControls
+}
+
+// loadRun reads the saved run, a run of another version of the game or of other levels is removed
+func (g *game) loadRun() *savedRun {
+	data, err := g.storage.Load(runKey)
+	if err != nil || data == nil {
+		if err != nil {
+			fmt.Println(err)
+		}
+		return nil
+	}
+
+	var run savedRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		fmt.Println("cannot decode the saved run:", err)
+		return nil
+	}
+
+	if run.Version != runVersion || run.Levels != levelsHash(g.levels) {
+		fmt.Println("the saved run was played with another version of the game or other levels")
+		g.removeRun()
+		return nil
+	}
+
+	return &run
+}
+
+// levelsHash identifies the levels a run is played with
+func levelsHash(l levels.Levels) string {
+	data, err := json.Marshal(l)
+	if err != nil {
+		fmt.Println(err)
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// startRun records a new run, the previous save is lost
+func (g *game) startRun(header live.Header) {
+	g.run = &savedRun{Version: runVersion, Levels: levelsHash(g.levels), Header: header}
+	g.removeRun()
+}
+
+// saveRun saves the run being played, it can be continued from the intro screen
+func (g *game) saveRun() {
+	if g.run == nil {
+		return
+	}
+
+	data, err := json.Marshal(g.run)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := g.storage.Save(runKey, data); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	g.savedRun = &savedRun{Version: g.run.Version, Levels: g.run.Levels, Header: g.run.Header, Events: slices.Clone(g.run.Events)}
+}
+
+// endRun stops recording once the game is over, there is nothing to continue
+func (g *game) endRun() {
+	g.run = nil
+	g.removeRun()
+}
+
+func (g *game) removeRun() {
+	g.savedRun = nil
+	if err := g.storage.Remove(runKey); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// autosaveRun saves the run from time to time while playing
+func (g *game) autosaveRun() {
+	if g.run == nil {
+		return
+	}
+
+	g.run.autosave++
+	if g.run.autosave >= autosaveFrames {
+		g.run.autosave = 0
+		g.saveRun()
+	}
+}
+
+// updateContinueButton shows the continue button on the intro screen while a run is saved
+func (g *game) updateContinueButton() {
+	if (g.savedRun != nil) == g.continueShown {
+		return
+	}
+
+	g.continueShown = g.savedRun != nil
+	if !g.continueShown {
+		g.openScreenButtons.Remove(continueCaption)
+		return
+	}
+
+	g.openScreenButtons.New(continueCaption, 40, 360, 130, 28, g.continueRun)
+}
+
+// continueRun starts the saved run again, the robots play its events until the player takes over
+func (g *game) continueRun() {
+	saved := g.savedRun
+	g.leaveOnline()
+
+	oldCaptions := []string{g.difficultyCaption(), g.playersCaption()}
+	g.difficulty = saved.Header.Difficulty
+	g.coop = saved.Header.Players == 2
+	g.openScreenButtons.Rename(oldCaptions[0], g.difficultyCaption())
+	g.openScreenButtons.Rename(oldCaptions[1], g.playersCaption())
+
+	inputs := []*scriptedControls{
+		{pressed: map[controls.Action]bool{}},
+		{pressed: map[controls.Action]bool{}},
+	}
+	playerControls := []controls.Controls{g.controls, g.secondControls}
+	g.controls, g.secondControls = inputs[0], inputs[1]
+	g.rnd = newRand(saved.Header.Seed)
+	g.init()
+	g.controls, g.secondControls = playerControls[0], playerControls[1]
+
+	g.run = &savedRun{Version: saved.Version, Levels: saved.Levels, Header: saved.Header, Events: slices.Clone(saved.Events)}
+	g.resuming = &resuming{
+		events:   saved.Events,
+		lastDraw: bytes.LastIndexByte(saved.Events, eventDraw),
+		inputs:   inputs,
+	}
+	g.drawStatus = statusDrawResume
+}
+
+// drawResume plays the saved events without sound, then the run is paused so the player can get ready
+func (g *game) drawResume(screen *ebiten.Image) {
+	r := g.resuming
+	playing, audioContext := g.sounds, g.audioContext
+	g.sounds, g.audioContext = sounds{}, nil
+	end := min(r.played+resumeEventsPerFrame, len(r.events))
+	for i := r.played; i < end; i++ {
+		g.playEvent(r.inputs, r.events[i], i == r.lastDraw)
+	}
+	r.played = end
+	g.sounds, g.audioContext = playing, audioContext
+
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(g.images.titleImage, op)
+	screen.DrawImage(g.images.overlay, op)
+	gametext.Draw(screen, fmt.Sprintf("Resuming your run... %d%%", 100*r.played/max(len(r.events), 1)), 215, 240)
+
+	if r.played < len(r.events) {
+		return
+	}
+
+	playerControls := []controls.Controls{g.controls, g.secondControls}
+	for i, p := range g.players {
+		p.controls = playerControls[i]
+	}
+	g.resuming = nil
+	g.pause()
+}
@@ -0,0 +1,158 @@
+package gameloop
+
+import (
+	"reflect"
+	"slices"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/live"
+	"testing"
+)
+
+// memoryStorage keeps the saved data for the test
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Load(key string) ([]byte, error) {
+	return m[key], nil
+}
+
+func (m memoryStorage) Save(key string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func (m memoryStorage) Remove(key string) error {
+	delete(m, key)
+	return nil
+}
+
+func TestSavedRunResumes(t *testing.T) {
+	tests := []struct {
+		name  string
+		seed  uint64
+		ticks int
+		// restart restarts the level from the pause screen at this tick, if any
+		restart int
+	}{
+		{name: "first level", seed: 1, ticks: 600},
+		{name: "later levels", seed: 2, ticks: 3000},
+		{name: "restarted level", seed: 3, ticks: 2000, restart: 1200},
+	}
+
+	t.Chdir("../..")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := memoryStorage{}
+			played := newRunSimulation(store, test.seed)
+			g := played.game
+			headless(func() {
+				g.startRun(live.Header{Seed: test.seed, Difficulty: g.difficulty, Players: 1})
+				for tick := range test.ticks {
+					played.controls.set(chaseBot(tick, played.State()))
+					g.recordInput()
+					g.handleShoot()
+					g.handleNavigation()
+					if tick == test.restart && test.restart > 0 {
+						g.restartLevel()
+						g.recordEvent(eventRestart)
+					}
+					g.drawPlayfield(nil)
+					g.recordEvent(eventDraw)
+					played.tick++
+				}
+				g.saveRun()
+			})
+
+			resumed := newRunSimulation(store, 0)
+			r := resumed.game
+			headless(func() {
+				r.savedRun = r.loadRun()
+				r.continueRun()
+				for _, event := range r.resuming.events {
+					r.playEvent(r.resuming.inputs, event, false)
+				}
+			})
+			resumed.tick = played.tick
+
+			if want, got := played.State(), resumed.State(); !reflect.DeepEqual(want, got) {
+				t.Fatalf("resumed state differs\nwant %+v\ngot  %+v", want, got)
+			}
+			if g.rnd.Uint64() != r.rnd.Uint64() {
+				t.Fatal("resumed random state differs")
+			}
+		})
+	}
+}
+
+func TestSavedRunOfAnotherGameIsDropped(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(run *savedRun, g *game)
+	}{
+		{name: "same game"},
+		{name: "older version", change: func(run *savedRun, g *game) {
+			run.Version--
+		}},
+		{name: "other levels", change: func(run *savedRun, g *game) {
+			g.levels.Levels[0].Speed++
+		}},
+	}
+
+	t.Chdir("../..")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := memoryStorage{}
+			played := newRunSimulation(store, 1)
+			g := played.game
+			headless(func() {
+				g.startRun(live.Header{Seed: 1, Difficulty: g.difficulty, Players: 1})
+				g.recordEvent(eventDraw)
+			})
+
+			resumed := newRunSimulation(store, 0)
+			if test.change != nil {
+				resumed.game.levels.Levels = slices.Clone(resumed.game.levels.Levels)
+				test.change(g.run, resumed.game)
+			}
+			g.saveRun()
+
+			loaded := resumed.game.loadRun()
+			if want := test.change == nil; (loaded != nil) != want || (store[runKey] != nil) != want {
+				t.Fatalf("loaded %v, saved %v, want both %v", loaded != nil, store[runKey] != nil, want)
+			}
+		})
+	}
+}
+
+// newRunSimulation is a simulation saving its runs, with the second robot idle
+func newRunSimulation(store memoryStorage, seed uint64) *Simulation {
+	s := NewSimulation(SimulationOptions{Seed: seed})
+	s.game.storage = store
+	s.game.secondControls = &scriptedControls{pressed: map[controls.Action]bool{}}
+	s.game.openScreenButtons = button.New()
+
+	return s
+}
+
+// chaseBot fires from time to time and follows the capsules, or the ufos in turn
+func chaseBot(tick int, state State) []controls.Action {
+	actions := []controls.Action{}
+	if tick%15 == 0 {
+		actions = append(actions, controls.Fire)
+	}
+
+	target := 300.0
+	if len(state.Capsules) > 0 {
+		target = state.Capsules[0].X - 15
+	} else if len(state.Ufos) > 0 {
+		target = state.Ufos[(tick/100)%len(state.Ufos)].X
+	}
+
+	if state.Player.X < target-5 {
+		actions = append(actions, controls.Right)
+	} else if state.Player.X > target+5 {
+		actions = append(actions, controls.Left)
+	}
+
+	return actions
+}
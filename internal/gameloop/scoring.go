@@ -0,0 +1,85 @@
+package gameloop
+
+import (
+	"image/color"
+	"spaceinvader/internal/defaultconfig"
+	"strconv"
+)
+
+const (
+	// chainStep is how many kills in a row raise the chain multiplier
+	chainStep          = 5
+	maxChainMultiplier = 4
+	bossHitPoints      = 5
+	levelClearPoints   = 100
+	lifeBonusPoints    = 50
+)
+
+var (
+	pointsColor = color.RGBA{255, 255, 255, 255}
+	bonusColor  = color.RGBA{255, 220, 60, 255}
+)
+
+// scoring keeps the shots and the lives lost of the current level, and the kill chain
+type scoring struct {
+	shots int
+	hits  int
+	// chain counts the kills since the last missed shot or lost life
+	chain     int
+	livesLost int
+}
+
+func (g *game) chainMultiplier() int {
+	return min(1+g.scoring.chain/chainStep, maxChainMultiplier)
+}
+
+// addKillScore scores a destroyed enemy with the chain multiplier
+func (g *game) addKillScore(p *player, points int, x, y float64) {
+	multiplier := g.chainMultiplier()
+	g.scoring.chain++
+	g.addPoints(p, points*multiplier, x, y, pointsColor)
+}
+
+// addPoints adds to the score of the player and the team, and shows the points rising from the position
+func (g *game) addPoints(p *player, points int, x, y float64, textColor color.RGBA) {
+	if g.powerUpActive(defaultconfig.ApsaraDB) {
+		points *= 2
+	}
+
+	g.score += points
+	p.score += points
+	g.addFloatingText("+"+strconv.Itoa(points), x, y, textColor)
+}
+
+func (g *game) shotFired() {
+	g.scoring.shots++
+}
+
+// shotHit counts a bullet hitting at least one target
+func (g *game) shotHit() {
+	g.scoring.hits++
+}
+
+// breakChain resets the multiplier after a missed shot or a lost life
+func (g *game) breakChain() {
+	g.scoring.chain = 0
+}
+
+// levelCleared adds the clear bonus, raised by the accuracy of the level, and the bonus of the lives left
+func (g *game) levelCleared() {
+	g.achieve(achievementLevelCleared)
+
+	accuracy := 0.0
+	if g.scoring.shots > 0 {
+		accuracy = float64(g.scoring.hits) / float64(g.scoring.shots)
+	}
+
+	bonus := int(float64(levelClearPoints*g.level)*(1+accuracy)) + lifeBonusPoints*g.totalLives()
+	g.score += bonus
+	g.addFloatingText("Level clear +"+strconv.Itoa(bonus), 230, 220, bonusColor)
+	g.addFloatingText("Accuracy "+strconv.Itoa(int(accuracy*100))+"%", 255, 250, bonusColor)
+
+	g.scoring.shots = 0
+	g.scoring.hits = 0
+	g.scoring.livesLost = 0
+}
@@ -0,0 +1,290 @@
+package gameloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/difficulty"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/storage"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	settingsKey = "settings"
+	// shakeFrames is how long the screen shakes when a robot is hit
+	shakeFrames    = 20
+	shakeAmplitude = 6
+)
+
+var (
+	volumes      = []float64{0, 0.25, 0.5, 0.75, 1}
+	windowScales = []float64{1, 1.5, 2}
+)
+
+// settings are the options of the player, saved on every change.
+// Broadcast streams the games with the name of the player to the viewers of the live games,
+// SyncAchievements sends the achievements to the score server with the saved scores
+type settings struct {
+	MusicVolume      float64 `json:"musicVolume"`
+	SfxVolume        float64 `json:"sfxVolume"`
+	Muted            bool    `json:"muted"`
+	Fullscreen       bool    `json:"fullscreen"`
+	Vsync            bool    `json:"vsync"`
+	WindowScale      float64 `json:"windowScale"`
+	Shake            bool    `json:"shake"`
+	Difficulty       string  `json:"difficulty"`
+	Broadcast        bool    `json:"broadcast"`
+	SyncAchievements bool    `json:"syncAchievements"`
+}
+
+var defaultSettings = settings{
+	MusicVolume: 1,
+	SfxVolume:   1,
+	Vsync:       true,
+	WindowScale: 1,
+	Shake:       true,
+	Difficulty:  difficulty.Normal.Name,
+}
+
+// loadSettings returns the saved settings, the ones missing keep their default
+func loadSettings(store storage.Storage) settings {
+	s := defaultSettings
+	data, err := store.Load(settingsKey)
+	if err != nil {
+		fmt.Println(err)
+		return s
+	}
+
+	if data != nil {
+		if err := json.Unmarshal(data, &s); err != nil {
+			fmt.Println("cannot decode settings:", err)
+			return defaultSettings
+		}
+	}
+
+	return s
+}
+
+func (g *game) saveSettings() {
+	data, err := json.Marshal(g.settings)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := g.storage.Save(settingsKey, data); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// defaultDifficulty is the game mode chosen in the settings, the game starts with it
+func (s settings) defaultDifficulty() difficulty.Difficulty {
+	for _, d := range difficulty.Presets() {
+		if d.Name == s.Difficulty {
+			return d
+		}
+	}
+
+	return difficulty.Normal
+}
+
+func (s settings) musicVolume() float64 {
+	if s.Muted {
+		return 0
+	}
+
+	return s.MusicVolume
+}
+
+func (s settings) sfxVolume() float64 {
+	if s.Muted {
+		return 0
+	}
+
+	return s.SfxVolume
+}
+
+// applySettings sets the window and the volumes to the settings, as loaded
+func (g *game) applySettings() {
+	ebiten.SetVsyncEnabled(g.settings.Vsync)
+	if g.settings.Fullscreen {
+		ebiten.SetFullscreen(true)
+	}
+	g.resizeWindow()
+	g.applyVolumes()
+}
+
+func (g *game) applyVolumes() {
+	if g.sounds.bgMusic != nil {
+		g.sounds.bgMusic.SetVolume(g.settings.musicVolume())
+		if g.settings.musicVolume() == 0 {
+			g.sounds.bgMusic.Pause()
+		}
+	}
+
+	if g.sounds.explosionSnd != nil {
+		g.sounds.explosionSnd.SetVolume(g.settings.sfxVolume())
+	}
+
+	if g.sounds.mothership != nil {
+		g.sounds.mothership.SetVolume(g.settings.sfxVolume())
+	}
+}
+
+// resizeWindow scales the window on desktop, the browser scales the canvas to the page itself
+func (g *game) resizeWindow() {
+	if !ebiten.IsFullscreen() {
+		ebiten.SetWindowSize(int(ScreenW*g.settings.WindowScale), int(ScreenH*g.settings.WindowScale))
+	}
+}
+
+func (g *game) initiateSettingsButtons() {
+	g.openScreenButtons.New("Settings", 185, 360, 130, 28, func() {
+		g.openSettings(statusDrawIntro)
+	})
+
+	g.settingsButtons = button.New()
+	g.addSettingButton(100, 110, func() string {
+		return "Music: " + volumeCaption(g.settings.MusicVolume)
+	}, func() {
+		g.settings.MusicVolume = nextValue(volumes, g.settings.MusicVolume)
+	})
+	g.addSettingButton(100, 150, func() string {
+		return "Sound effects: " + volumeCaption(g.settings.SfxVolume)
+	}, func() {
+		g.settings.SfxVolume = nextValue(volumes, g.settings.SfxVolume)
+	})
+	g.addSettingButton(100, 190, func() string {
+		return "Mute: " + onOff(g.settings.Muted)
+	}, func() {
+		g.settings.Muted = !g.settings.Muted
+	})
+	g.addSettingButton(100, 230, func() string {
+		return "Screen shake: " + onOff(g.settings.Shake)
+	}, func() {
+		g.settings.Shake = !g.settings.Shake
+	})
+	g.addSettingButton(100, 270, func() string {
+		return "Default mode: " + capitalize(g.settings.Difficulty)
+	}, func() {
+		names := []string{}
+		for _, d := range difficulty.Presets() {
+			names = append(names, d.Name)
+		}
+		g.settings.Difficulty = nextValue(names, g.settings.Difficulty)
+	})
+	g.addSettingButton(330, 110, g.fullscreenCaption, func() {
+		g.settings.Fullscreen = !ebiten.IsFullscreen()
+		ebiten.SetFullscreen(g.settings.Fullscreen)
+		g.resizeWindow()
+	})
+	g.addSettingButton(330, 150, func() string {
+		return "Vsync: " + onOff(g.settings.Vsync)
+	}, func() {
+		g.settings.Vsync = !g.settings.Vsync
+		ebiten.SetVsyncEnabled(g.settings.Vsync)
+	})
+	g.addSettingButton(330, 190, func() string {
+		return "Window: x" + strconv.FormatFloat(g.settings.WindowScale, 'g', -1, 64)
+	}, func() {
+		g.settings.WindowScale = nextValue(windowScales, g.settings.WindowScale)
+		g.resizeWindow()
+	})
+	// The games can only be broadcast to a score server streaming them
+	if defaultconfig.LiveUrl != "" {
+		g.addSettingButton(330, 270, func() string {
+			return "Broadcast games: " + onOff(g.settings.Broadcast)
+		}, func() {
+			g.settings.Broadcast = !g.settings.Broadcast
+		})
+	}
+	// The achievements can only be synced to a score server keeping them
+	if defaultconfig.AchievementsUrl != "" {
+		g.addSettingButton(330, 310, func() string {
+			return "Sync achievements: " + onOff(g.settings.SyncAchievements)
+		}, func() {
+			g.settings.SyncAchievements = !g.settings.SyncAchievements
+		})
+	}
+	g.settingsButtons.New("Back", 270, 350, 100, 28, func() {
+		g.drawStatus = g.settingsBack
+	})
+}
+
+// addSettingButton adds a cycle button which saves the settings and applies the volumes on every change
+func (g *game) addSettingButton(x, y int, caption func() string, next func()) {
+	g.addCycleButton(g.settingsButtons, x, y, 210, caption, func() {
+		next()
+		g.applyVolumes()
+		g.saveSettings()
+	})
+}
+
+func (g *game) openSettings(back drawStatus) {
+	g.settingsBack = back
+	g.drawStatus = statusDrawSettings
+}
+
+func (g *game) fullscreenCaption() string {
+	return "Fullscreen (F11): " + onOff(g.settings.Fullscreen)
+}
+
+// toggleFullScreen switches the fullscreen with F11, the setting and its button follow
+func (g *game) toggleFullScreen() {
+	oldCaption := g.fullscreenCaption()
+	g.settings.Fullscreen = !ebiten.IsFullscreen()
+	ebiten.SetFullscreen(g.settings.Fullscreen)
+	g.resizeWindow()
+	g.settingsButtons.Rename(oldCaption, g.fullscreenCaption())
+	g.saveSettings()
+}
+
+// shakeOffset moves the frame while the screen shakes, back and forth and fading out
+func (g *game) shakeOffset() (float64, float64) {
+	if g.gameStatus.shakeTimer == 0 {
+		return 0, 0
+	}
+
+	g.gameStatus.shakeTimer--
+	if !g.settings.Shake {
+		return 0, 0
+	}
+
+	t := float64(g.gameStatus.shakeTimer)
+	amplitude := shakeAmplitude * t / shakeFrames
+
+	return amplitude * math.Sin(t*2.1), amplitude * math.Cos(t*1.7)
+}
+
+func volumeCaption(volume float64) string {
+	if volume == 0 {
+		return "off"
+	}
+
+	return strconv.Itoa(int(volume*100)) + "%"
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+func (g *game) drawSettings(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	background := g.images.titleImage
+	if g.settingsBack == statusDrawPause {
+		background = g.images.frame
+	}
+	screen.DrawImage(background, op)
+	screen.DrawImage(g.images.overlay, op)
+	gametext.Draw(screen, "SETTINGS", 280, 70)
+	g.settingsButtons.Render(screen)
+}
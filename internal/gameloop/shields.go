@@ -0,0 +1,158 @@
+package gameloop
+
+import (
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"spaceinvader/internal/levels"
+	"spaceinvader/internal/sprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	shieldCellSize = 4
+	// shieldBlast is how many cells around a hit can be destroyed
+	shieldBlast = 2
+)
+
+var shieldColor = color.RGBA{90, 230, 120, 255}
+
+// shield is a bunker made of cells, the damaged cells are erased from its image
+type shield struct {
+	x, y  float64
+	cells [][]bool
+	// image is created on the first draw, so it's never created when running headless
+	image *ebiten.Image
+}
+
+func newShields(config levels.Shields) []*shield {
+	shape := config.Cells()
+	width := float64(len(shape[0]) * shieldCellSize)
+	shields := make([]*shield, 0, config.Count)
+	for i := range config.Count {
+		s := &shield{
+			x: float64(ScreenW*(i+1))/float64(config.Count+1) - width/2,
+			y: config.Y,
+		}
+		for _, line := range shape {
+			row := make([]bool, len(line))
+			for column := range line {
+				row[column] = line[column] != '.'
+			}
+			s.cells = append(s.cells, row)
+		}
+		shields = append(shields, s)
+	}
+
+	return shields
+}
+
+// hit checks if the sprite hits a cell, then blows a hole around it.
+// fromBelow is set for the bullets, so the holes start at the bottom of the shield
+func (s *shield) hit(sp sprite.Sprite, fromBelow bool, rnd *rand.Rand) bool {
+	top, bottom, left, right, ok := s.overlap(sp)
+	if !ok {
+		return false
+	}
+
+	for i := range bottom - top + 1 {
+		row := top + i
+		if fromBelow {
+			row = bottom - i
+		}
+
+		for column := left; column <= right; column++ {
+			if s.cells[row][column] {
+				s.blast(row, column, rnd)
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// erode erases every cell under the sprite, as the formation flies through the shield
+func (s *shield) erode(sp sprite.Sprite) {
+	top, bottom, left, right, ok := s.overlap(sp)
+	if !ok {
+		return
+	}
+
+	for row := top; row <= bottom; row++ {
+		for column := left; column <= right; column++ {
+			s.erase(row, column)
+		}
+	}
+}
+
+func (s *shield) blast(row, column int, rnd *rand.Rand) {
+	s.erase(row, column)
+	for dr := -shieldBlast; dr <= shieldBlast; dr++ {
+		for dc := -shieldBlast; dc <= shieldBlast; dc++ {
+			distance := abs(dr) + abs(dc)
+			if distance > 0 && distance <= shieldBlast && rnd.IntN(distance+1) == 0 {
+				s.erase(row+dr, column+dc)
+			}
+		}
+	}
+}
+
+func (s *shield) erase(row, column int) {
+	if row < 0 || row >= len(s.cells) || column < 0 || column >= len(s.cells[row]) || !s.cells[row][column] {
+		return
+	}
+
+	s.cells[row][column] = false
+	if s.image != nil {
+		s.image.SubImage(cellRect(row, column)).(*ebiten.Image).Clear()
+	}
+}
+
+// overlap returns the range of the cells under the sprite
+func (s *shield) overlap(sp sprite.Sprite) (int, int, int, int, bool) {
+	rows, columns := len(s.cells), len(s.cells[0])
+	left := int((sp.GetX() - s.x) / shieldCellSize)
+	right := int((sp.GetX() + sp.GetWidth() - s.x) / shieldCellSize)
+	top := int((sp.GetY() - s.y) / shieldCellSize)
+	bottom := int((sp.GetY() + sp.GetHeight() - s.y) / shieldCellSize)
+	if sp.GetX()+sp.GetWidth() < s.x || sp.GetY()+sp.GetHeight() < s.y || left >= columns || top >= rows {
+		return 0, 0, 0, 0, false
+	}
+
+	return max(top, 0), min(bottom, rows-1), max(left, 0), min(right, columns-1), true
+}
+
+func (s *shield) draw(screen *ebiten.Image) {
+	if screen == nil {
+		return
+	}
+
+	if s.image == nil {
+		s.image = ebiten.NewImage(len(s.cells[0])*shieldCellSize, len(s.cells)*shieldCellSize)
+		for row := range s.cells {
+			for column, filled := range s.cells[row] {
+				if filled {
+					s.image.SubImage(cellRect(row, column)).(*ebiten.Image).Fill(shieldColor)
+				}
+			}
+		}
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(s.x, s.y)
+	screen.DrawImage(s.image, op)
+}
+
+func cellRect(row, column int) image.Rectangle {
+	return image.Rect(column*shieldCellSize, row*shieldCellSize, (column+1)*shieldCellSize, (row+1)*shieldCellSize)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
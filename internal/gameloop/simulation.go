@@ -0,0 +1,207 @@
+package gameloop
+
+import (
+	"maps"
+	"slices"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/difficulty"
+	"spaceinvader/internal/levels"
+	"spaceinvader/internal/sprite"
+)
+
+// Script returns the actions held down in the given tick, it can look at the current state to aim
+type Script func(tick int, state State) []controls.Action
+
+// Position of a sprite on the screen
+type Position struct {
+	X, Y float64
+}
+
+// State is a snapshot of a simulated game
+type State struct {
+	Tick    int
+	Score   int
+	Lives   int
+	Level   int
+	Player  Position
+	Ufos    []Position
+	Bombs   []Position
+	Bullets int
+	// Lasers are the top of the enemy laser beams, including the warning lines
+	Lasers []Position
+	// PowerUps has the frames left of the active power-ups
+	PowerUps map[defaultconfig.AlibabaServiceType]int
+	Capsules []Position
+	// Invulnerable is set while the player respawns and blinks after losing a life
+	Invulnerable bool
+	// Mothership is nil unless the mothership is flying
+	Mothership *Position
+	// Endless is set once the last level is cleared
+	Endless bool
+	Lost    bool
+}
+
+// Ended reports if the game was lost, it can't be won as the endless waves keep coming
+func (s State) Ended() bool {
+	return s.Lost
+}
+
+// SimulationOptions configures a headless game, only the seed is required
+type SimulationOptions struct {
+	Seed uint64
+	// Script is the scripted input, the player stays still when it is nil
+	Script Script
+	// Difficulty defaults to normal
+	Difficulty difficulty.Difficulty
+}
+
+// Simulation runs the game loop without a window, audio or real input, with a fixed seed
+type Simulation struct {
+	game     *game
+	script   Script
+	controls *scriptedControls
+	tick     int
+}
+
+type scriptedControls struct {
+	pressed map[controls.Action]bool
+}
+
+func (c *scriptedControls) Pressed(action controls.Action) bool {
+	return c.pressed[action]
+}
+
+func (c *scriptedControls) set(actions []controls.Action) {
+	clear(c.pressed)
+	for _, action := range actions {
+		c.pressed[action] = true
+	}
+}
+
+// NewSimulation creates a headless game
+func NewSimulation(options SimulationOptions) *Simulation {
+	if options.Difficulty.Name == "" {
+		options.Difficulty = difficulty.Normal
+	}
+
+	s := &Simulation{
+		script:   options.Script,
+		controls: &scriptedControls{pressed: map[controls.Action]bool{}},
+	}
+	s.game = &game{
+		controls:   s.controls,
+		rnd:        newRand(options.Seed),
+		drawStatus: statusDrawGame,
+		levels:     levels.Default(),
+		difficulty: options.Difficulty,
+		codex:      loadCodex(nil),
+		best:       loadBestScores(nil),
+	}
+	headless(func() {
+		s.game.loadSprites()
+		s.game.init()
+	})
+
+	return s
+}
+
+// Step simulates one update and draw frame
+func (s *Simulation) Step() {
+	if s.State().Ended() {
+		return
+	}
+
+	if s.script != nil {
+		s.controls.set(s.script(s.tick, s.State()))
+	}
+
+	headless(func() {
+		s.game.handleShoot()
+		s.game.handleNavigation()
+		s.game.drawPlayfield(nil)
+	})
+	s.tick++
+}
+
+// headless runs the simulation without images, the setting is restored for the game in the window
+func headless(run func()) {
+	defer sprite.SetHeadless(sprite.SetHeadless(true))
+	run()
+}
+
+// Run simulates the given number of ticks, or less if the game ends, and returns the final state
+func (s *Simulation) Run(ticks int) State {
+	for i := 0; i < ticks && !s.State().Ended(); i++ {
+		s.Step()
+	}
+
+	return s.State()
+}
+
+// State returns the current state of the simulation
+func (s *Simulation) State() State {
+	g := s.game
+	// The simulation plays a single robot
+	player := g.players[0]
+
+	var mothership *Position
+	if g.ufos.mothershipFlying() {
+		m := g.ufos.mothership.sprite
+		mothership = &Position{X: m.GetX(), Y: m.GetY()}
+	}
+
+	return State{
+		Tick:         s.tick,
+		Score:        g.score,
+		Lives:        player.lives,
+		Level:        g.level,
+		Player:       Position{X: player.sprite.GetX(), Y: player.sprite.GetY()},
+		Ufos:         positions(g.ufos.ufoList),
+		Bombs:        bombPositions(g.ufos.bombs),
+		Lasers:       laserPositions(g.ufos.lasers),
+		Bullets:      len(player.bullets),
+		PowerUps:     maps.Clone(g.powerUps.active),
+		Capsules:     capsulePositions(g.powerUps.capsules),
+		Mothership:   mothership,
+		Invulnerable: player.invulnerable(),
+		Endless:      g.isEndless(),
+		Lost:         g.gameStatus.loose,
+	}
+}
+
+func positions(list map[int]sprite.Sprite) []Position {
+	result := make([]Position, 0, len(list))
+	for _, id := range slices.Sorted(maps.Keys(list)) {
+		result = append(result, Position{X: list[id].GetX(), Y: list[id].GetY()})
+	}
+
+	return result
+}
+
+func bombPositions(bombs map[int]*projectile) []Position {
+	list := map[int]sprite.Sprite{}
+	for id, b := range bombs {
+		list[id] = b.sprite
+	}
+
+	return positions(list)
+}
+
+func laserPositions(lasers []*enemyLaser) []Position {
+	result := make([]Position, 0, len(lasers))
+	for _, l := range lasers {
+		result = append(result, Position{X: l.x, Y: l.y})
+	}
+
+	return result
+}
+
+func capsulePositions(capsules map[int]*capsule) []Position {
+	list := map[int]sprite.Sprite{}
+	for id, c := range capsules {
+		list[id] = c.sprite
+	}
+
+	return positions(list)
+}
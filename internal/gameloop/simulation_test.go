@@ -0,0 +1,155 @@
+package gameloop
+
+import (
+	"math"
+	"slices"
+	"spaceinvader/internal/controls"
+	"testing"
+)
+
+// maxTicks is long enough to clear a level or to be hit by every bomb
+const maxTicks = 20000
+
+func TestSimulation(t *testing.T) {
+	tests := []struct {
+		name   string
+		seed   uint64
+		script Script
+		// done stops the game before it ends
+		done  func(state State) bool
+		check func(t *testing.T, start, end State, lives []int)
+	}{
+		{
+			name:   "shooting the 15 ufos clears level 1",
+			seed:   1,
+			script: shootBot(),
+			done: func(state State) bool {
+				return state.Level == 2
+			},
+			check: func(t *testing.T, start, end State, lives []int) {
+				if len(start.Ufos) != 15 {
+					t.Fatalf("level 1 starts with %d ufos, want 15", len(start.Ufos))
+				}
+				if end.Level != 2 || end.Score == 0 {
+					t.Fatalf("the level was not cleared, state %+v", end)
+				}
+			},
+		},
+		{
+			name:   "3 bomb hits end the game",
+			seed:   1,
+			script: bombBot,
+			check: func(t *testing.T, start, end State, lives []int) {
+				if !end.Ended() {
+					t.Fatalf("the game did not end, state %+v", end)
+				}
+				// A life is lost on each hit, the game ends with the last one
+				if want := []int{3, 2, 1, 0}; !slices.Equal(lives, want) {
+					t.Fatalf("lives went %v, want %v", lives, want)
+				}
+			},
+		},
+	}
+
+	// The images are read from the root of the module, the headless sprites take their size
+	t.Chdir("../..")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewSimulation(SimulationOptions{Seed: test.seed, Script: test.script})
+			start := s.State()
+			lives := []int{start.Lives}
+			for range maxTicks {
+				state := s.State()
+				if state.Ended() || test.done != nil && test.done(state) {
+					break
+				}
+
+				s.Step()
+				if state := s.State(); state.Lives != lives[len(lives)-1] {
+					lives = append(lives, state.Lives)
+				}
+			}
+
+			test.check(t, start, s.State(), lives)
+		})
+	}
+}
+
+// shootBot walks to where the nearest ufo will be as a bullet gets there, and fires once under it.
+// The speeds are taken from the last tick
+func shootBot() Script {
+	var last State
+	return func(tick int, state State) []controls.Action {
+		defer func() { last = state }()
+		if len(state.Ufos) == 0 {
+			return nil
+		}
+
+		target, aimed := math.Inf(1), false
+		for i, ufo := range state.Ufos {
+			speed := 0.0
+			if len(last.Ufos) == len(state.Ufos) {
+				// An exploding ufo stands still, the formation around it keeps moving
+				if ufo == last.Ufos[i] {
+					continue
+				}
+				speed = ufo.X - last.Ufos[i].X
+			}
+			// The bullets slow down on the way up, a fiftieth of the way is left behind on every tick.
+			// They leave from the middle of the robot, 50 wide, and are aimed at the middle of the ufo, 40 wide
+			flight := 50 * math.Log(state.Player.Y/(ufo.Y+20))
+			x := bounce(ufo.X+speed*flight, ScreenW-40) - 5
+			if math.Abs(x-state.Player.X) < math.Abs(target-state.Player.X) {
+				target = x
+			}
+			aimed = aimed || math.Abs(x-state.Player.X) < 8
+		}
+
+		actions := moveTo(state.Player.X, target)
+		if aimed && tick%2 == 0 {
+			actions = append(actions, controls.Fire)
+		}
+
+		return actions
+	}
+}
+
+// bombBot stands under the lowest bomb, the bombs are 20 wide
+func bombBot(tick int, state State) []controls.Action {
+	if len(state.Bombs) == 0 {
+		return nil
+	}
+
+	lowest := state.Bombs[0]
+	for _, bomb := range state.Bombs {
+		if bomb.Y > lowest.Y {
+			lowest = bomb
+		}
+	}
+
+	return moveTo(state.Player.X, lowest.X-15)
+}
+
+// bounce folds x back onto the screen, the ufos turn at its edges
+func bounce(x, width float64) float64 {
+	x = math.Mod(x, 2*width)
+	if x < 0 {
+		x += 2 * width
+	}
+	if x > width {
+		x = 2*width - x
+	}
+
+	return x
+}
+
+func moveTo(x, target float64) []controls.Action {
+	switch {
+	case x < target-3:
+		return []controls.Action{controls.Right}
+	case x > target+3:
+		return []controls.Action{controls.Left}
+	}
+
+	return nil
+}
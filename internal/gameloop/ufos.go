@@ -1,22 +1,24 @@
 package gameloop
 
 import (
-	"math/rand"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"spaceinvader/internal/levels"
 	"spaceinvader/internal/sprite"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-func newUfoList(playerSprite sprite.Sprite, explosionCallback func(sprite.Sprite), hitCallback func(sprite.Sprite)) *ufos {
+func newUfoList(rnd *rand.Rand, enemies map[string]levels.Enemy, explosionCallback func(sprite.Sprite), hitCallback func(sprite.Sprite) bool) *ufos {
 	u := &ufos{
-		moveOffset:        2,
+		rnd:               rnd,
+		enemies:           enemies,
 		ufoList:           map[int]sprite.Sprite{},
-		bombs:             map[int]sprite.Sprite{},
-		playerSprite:      playerSprite,
+		bombs:             map[int]*projectile{},
 		hitCallback:       hitCallback,
 		explosionCallback: explosionCallback,
-
-		randDelay: 200,
 	}
 	u.explosionImages = u.getExplosionImages()
 
@@ -24,78 +26,132 @@ func newUfoList(playerSprite sprite.Sprite, explosionCallback func(sprite.Sprite
 }
 
 type ufos struct {
-	ufoList           map[int]sprite.Sprite
-	playerSprite      sprite.Sprite
-	hitCallback       func(sprite.Sprite)
+	rnd     *rand.Rand
+	enemies map[string]levels.Enemy
+	level   levels.Level
+	ufoList map[int]sprite.Sprite
+	types   map[int]levels.Enemy
+	damage  map[int]int
+	dives   map[int]*dive
+	descent float64
+	// players are the robots still in the game, they are set by the game
+	players           []sprite.Sprite
+	hitCallback       func(sprite.Sprite) bool
 	explosionCallback func(sprite.Sprite)
 	explosionImages   []*ebiten.Image
 	moveOffset        float64
 	blockXLeft        float64
-	bombs             map[int]sprite.Sprite
+	bombs             map[int]*projectile
+	lasers            []*enemyLaser
+	formationSize     int
+	shields           []*shield
 	bombId            int
+	boss              *boss
+	mothership        *mothership
+	mothershipTimer   int
+	minionId          int
 	frameId           int
 	randDelay         int
 }
 
-func (u *ufos) init() {
-
-	ufoImages := [][]string{
-		[]string{
-			"internal/images/Ships/Spaceship.png",
-			"internal/images/Ships/Spaceship2.png",
-			"internal/images/Ships/Spaceship3.png",
-		},
-		[]string{
-			"internal/images/Ships/Spaceship4.png",
-			"internal/images/Ships/Spaceship5.png",
-			"internal/images/Ships/Spaceship6.png",
-		},
-		[]string{
-			"internal/images/Ships/Spaceship7.png",
-			"internal/images/Ships/Spaceship8.png",
-			"internal/images/Ships/Spaceship9.png",
-		},
+func (u *ufos) init(level levels.Level) {
+	u.level = level
+	u.moveOffset = level.Speed
+	u.blockXLeft = 0
+	u.frameId = 0
+	u.randDelay = level.FirstBombDelay
+	u.minionId = minionIdStart
+	u.types = map[int]levels.Enemy{}
+	u.damage = map[int]int{}
+	u.dives = map[int]*dive{}
+	u.descent = 0
+	u.formationSize = 0
+	u.shields = newShields(level.Shields)
+	if u.mothership != nil {
+		u.mothership.sprite.Close()
+		u.mothership = nil
 	}
-	ufoId := 0
-	for h := 0; h < 5; h++ {
-		for v := 0; v < 3; v++ {
-			u.ufoList[ufoId] =
-				sprite.New(
-					ufoImages[v],
-					40, 40,
-					sprite.SpiteOptions{
-						X:                            float64(1 + h*80),
-						Y:                            float64(45 + v*60),
-						Soft:                         true,
-						Id:                           ufoId,
-						Animate:                      true,
-						AfterAnimationImages:         u.explosionImages,
-						AfterAnimationCallback:       u.explosionCallback,
-						AfterAnimationAnimationDelay: 2,
-					},
-				)
-			ufoId++
+	u.mothershipTimer = u.nextMothershipDelay()
+	u.boss = nil
+	if level.Boss != nil {
+		u.boss = u.newBoss(*level.Boss)
+	}
+
+	rows := len(level.Rows)
+	for h := 0; h < level.Formation.Columns; h++ {
+		for v := 0; v < rows; v++ {
+			if !level.Formation.Filled(h, v) {
+				continue
+			}
+			ufoId := h*rows + v
+			enemy := u.enemies[level.Rows[v]]
+			if projectile, ok := level.Projectiles[level.Rows[v]]; ok {
+				enemy.Projectile = projectile
+			}
+			u.types[ufoId] = enemy
+			u.formationSize++
+			options := sprite.SpiteOptions{
+				X:                            level.Formation.Left + float64(h)*level.Formation.SpacingX,
+				Y:                            level.Formation.Top + float64(v)*level.Formation.SpacingY,
+				Soft:                         true,
+				Id:                           ufoId,
+				Animate:                      true,
+				AfterAnimationImages:         u.explosionImages,
+				AfterAnimationCallback:       u.explosionCallback,
+				AfterAnimationAnimationDelay: 2,
+			}
+			if enemy.Behavior == levels.BehaviorDiver {
+				options.CollisionSprites = u.players
+				options.CollisionCallback = u.ufoCollision
+			}
+			u.ufoList[ufoId] = sprite.New(enemy.Images, 40, 40, options)
 		}
 	}
 }
 
+// restart puts the formation back to how the current level started
+func (u *ufos) restart() {
+	for id, ufo := range u.ufoList {
+		ufo.Close()
+		delete(u.ufoList, id)
+	}
+
+	u.clearBombs()
+	u.init(u.level)
+}
+
+func (u *ufos) clearBombs() {
+	for id, bomb := range u.bombs {
+		bomb.sprite.Close()
+		delete(u.bombs, id)
+	}
+	u.lasers = nil
+}
+
 func (u *ufos) render(screen *ebiten.Image) (bool, bool) {
 	hitEnd := false
 	var bombingUfo sprite.Sprite
 
-	for h := 0; h < 5; h++ {
-		for v := 0; v < 3; v++ {
-			newX := u.blockXLeft + float64(1+h*80)
-			ufo := u.ufoList[h*3+v]
+	formation := u.level.Formation
+	rows := len(u.level.Rows)
+	for h := 0; h < formation.Columns; h++ {
+		for v := 0; v < rows; v++ {
+			newX := u.blockXLeft + formation.Left + float64(h)*formation.SpacingX
+			ufo := u.ufoList[h*rows+v]
 			if ufo == nil {
 				continue
 			}
 			ufo.Render(screen)
+			if u.dives[ufo.Id()] != nil {
+				u.renderDive(ufo, newX, formation.Top+float64(v)*formation.SpacingY+u.descent)
+				continue
+			}
+
 			if ufo.SetX(newX) == false {
 				hitEnd = true
 			}
 
-			if rand.Intn(8) == 3 {
+			if u.rnd.IntN(u.level.BombChance) == 0 {
 				bombingUfo = ufo
 			}
 		}
@@ -106,63 +162,147 @@ func (u *ufos) render(screen *ebiten.Image) (bool, bool) {
 		u.moveOffset = -u.moveOffset
 		// Speed up
 		if u.moveOffset > 0 {
-			u.moveOffset += 0.30
+			u.moveOffset += u.level.SpeedUp
 		}
-		for _, ufo := range u.ufoList {
+		u.descent += u.level.Descent
+		for _, id := range slices.Sorted(maps.Keys(u.ufoList)) {
+			ufo := u.ufoList[id]
+			// The divers follow the formation when they are back in their slot
+			if ufo == nil || id >= minionIdStart || u.dives[id] != nil {
+				continue
+			}
 			if ufo.GetY() > 380 {
 				return false, true
 			}
-			if ufo != nil {
-				ufo.MoveY(10)
-			}
+			ufo.MoveY(u.level.Descent)
 		}
 	} else {
 		u.blockXLeft += u.moveOffset
 	}
 
-	// Add bombs render
-	for _, rBomb := range u.bombs {
-		rBomb.Render(screen)
-		if !rBomb.IsMoving() {
-			delete(u.bombs, rBomb.Id())
-		}
-	}
+	u.startDive()
+
+	u.renderMinions(screen)
+	u.renderMothership(screen)
+	u.renderShields(screen)
+
+	u.renderProjectiles(screen)
 
 	if u.frameId == u.randDelay {
 
 		u.frameId = 0
-		u.randDelay = rand.Intn(120) + 20
+		u.randDelay = u.rnd.IntN(u.level.BombDelayMax-u.level.BombDelayMin) + u.level.BombDelayMin
 		if bombingUfo != nil {
-			u.bombId++
-			bomb := sprite.New(
-				[]string{
-					"internal/images/Objects/xff2.png",
-				},
-				20, 20,
-				sprite.SpiteOptions{
-					Id:               u.bombId,
-					AnimateOnMove:    true,
-					SoftY:            40,
-					SoftSpeedUp:      true,
-					CollisionSprites: []sprite.Sprite{u.playerSprite},
-					CollisionCallback: func(bombSprite sprite.Sprite, _ []sprite.Sprite) {
-						u.hitCallback(bombSprite)
-					},
-				},
-			)
-			bomb.SetX(bombingUfo.GetX())
-			bomb.SetY(bombingUfo.GetY())
-
-			bomb.Soft(true)
-			bomb.SetY(450)
-
-			u.bombs[u.bombId] = bomb
+			u.shoot(bombingUfo)
 		}
 	} else {
 		u.frameId++
 	}
 
-	return len(u.ufoList) == 0, false
+	if u.boss != nil && u.renderBoss(screen) {
+		u.boss = nil
+	}
+
+	return len(u.ufoList) == 0 && u.boss == nil, false
+}
+
+// progress is the part of the wave destroyed from 0 to 1, the health of the boss counts as ships
+func (u *ufos) progress() float64 {
+	total, left := u.formationSize, 0
+	for id := range u.ufoList {
+		if id < minionIdStart {
+			left++
+		}
+	}
+
+	if u.level.Boss != nil {
+		total += u.level.Boss.Health
+		if u.boss != nil {
+			left += u.boss.health
+		}
+	}
+
+	if total == 0 {
+		return 1
+	}
+
+	return float64(total-left) / float64(total)
+}
+
+// renderShields draws the shields, the ufos flying through them erase the cells they cover
+func (u *ufos) renderShields(screen *ebiten.Image) {
+	for _, s := range u.shields {
+		for _, ufo := range u.ufoList {
+			s.erode(ufo)
+		}
+		s.draw(screen)
+	}
+}
+
+// hitShield reports if the projectile hit a shield and damages the shield
+func (u *ufos) hitShield(projectile sprite.Sprite, fromBelow bool) bool {
+	for _, s := range u.shields {
+		if s.hit(projectile, fromBelow, u.rnd) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dropBomb drops a bomb from x, y falling towards targetX
+func (u *ufos) dropBomb(x, y, targetX float64) {
+	u.bombId++
+	bomb := sprite.New(
+		[]string{
+			"internal/images/Objects/xff2.png",
+		},
+		20, 20,
+		sprite.SpiteOptions{
+			Id:                u.bombId,
+			AnimateOnMove:     true,
+			SoftY:             u.level.BombSpeed,
+			SoftSpeedUp:       true,
+			CollisionSprites:  u.players,
+			CollisionCallback: u.bombCollision,
+		},
+	)
+	bomb.SetX(x)
+	bomb.SetY(y)
+
+	bomb.Soft(true)
+	bomb.SetX(targetX)
+	bomb.SetY(450)
+
+	u.bombs[u.bombId] = &projectile{sprite: bomb, kind: levels.ProjectileBomb}
+}
+
+// hitPlayers hits the robots a projectile collided with, it reports if the projectile is stopped
+func (u *ufos) hitPlayers(players []sprite.Sprite) bool {
+	stopped := false
+	for _, player := range players {
+		if u.hitCallback(player) {
+			stopped = true
+		}
+	}
+
+	return stopped
+}
+
+// nearestPlayer returns the robot closest to x, nil when no robot is left
+func (u *ufos) nearestPlayer(x float64) sprite.Sprite {
+	var nearest sprite.Sprite
+	for _, player := range u.players {
+		if nearest == nil || math.Abs(centerX(player)-x) < math.Abs(centerX(nearest)-x) {
+			nearest = player
+		}
+	}
+
+	return nearest
+}
+
+func centerX(sp sprite.Sprite) float64 {
+	return sp.GetX() + sp.GetWidth()/2
 }
 
 func (u *ufos) getExplosionImages() []*ebiten.Image {
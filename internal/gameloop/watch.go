@@ -0,0 +1,318 @@
+package gameloop
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"spaceinvader/internal/button"
+	"spaceinvader/internal/controls"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/live"
+	"spaceinvader/internal/netplay"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The stream events, an update is a byte with the inputs of the first player in the low 3 bits
+// and the inputs of the second player in the next 3 bits
+const (
+	eventDraw    byte = 1 << 6
+	eventRestart byte = 1 << 7
+)
+
+const (
+	// watchBuffer are the frames kept to play, the viewer catches up at once when it's further behind
+	watchBuffer = 60
+	// maxLiveGames are listed on the watch screen
+	maxLiveGames = 8
+)
+
+// watch is the watch screen, with the live games and the game being watched
+type watch struct {
+	games   []live.Game
+	loaded  chan []live.Game
+	buttons button.Button
+	message string
+	cancel  context.CancelFunc
+	joined  chan live.Viewer
+	viewer  live.Viewer
+	// replay plays the stream of the watched game with the inputs of the robots
+	replay *game
+	inputs []*scriptedControls
+	events []byte
+	ended  bool
+}
+
+func (g *game) initiateWatchButtons() {
+	g.watch = &watch{}
+	g.initiateLiveGameButtons()
+	// The games can only be watched from a score server streaming them
+	if defaultconfig.LiveUrl != "" {
+		g.openScreenButtons.New("Watch live", 330, 360, 130, 28, func() {
+			g.drawStatus = statusDrawWatch
+			g.refreshLiveGames()
+		})
+	}
+
+	g.watchingButtons = button.New()
+	g.watchingButtons.New("Stop watching", 500, 440, 130, 28, func() {
+		g.stopWatching()
+		g.drawStatus = statusDrawWatch
+		g.refreshLiveGames()
+	})
+}
+
+// startBroadcast streams the game to the spectator server, the seed and the difficulty let the viewers play it
+func (g *game) startBroadcast(seed uint64) {
+	g.stopBroadcast()
+	if defaultconfig.LiveSocketUrl == "" || !g.settings.Broadcast {
+		return
+	}
+
+	g.broadcast = live.Broadcast(defaultconfig.LiveSocketUrl, g.gameHeader(seed))
+}
+
+// gameHeader tells how to play the game again, the viewers and the saved runs start with it
+func (g *game) gameHeader(seed uint64) live.Header {
+	name := g.inputBox.Text()
+	if len(name) < 3 {
+		name = "Anonymous"
+	}
+
+	return live.Header{
+		Name:       name,
+		Seed:       seed,
+		Difficulty: g.difficulty,
+		Players:    g.playerCount(),
+	}
+}
+
+func (g *game) stopBroadcast() {
+	if g.broadcast == nil {
+		return
+	}
+
+	g.broadcast.Close()
+	g.broadcast = nil
+}
+
+// recordEvent sends the event to the viewers and keeps it in the run, to save it
+func (g *game) recordEvent(event byte) {
+	if g.broadcast != nil {
+		g.broadcast.Add([]byte{event}, g.score)
+	}
+
+	if g.run != nil {
+		g.run.Events = append(g.run.Events, event)
+	}
+}
+
+// recordInput records the inputs of an update, the second robot is only played in co-op
+func (g *game) recordInput() {
+	g.recordEvent(byte(inputOf(g.controls)) | byte(inputOf(g.secondControls))<<3)
+}
+
+// refreshLiveGames loads the live games in the background
+func (g *game) refreshLiveGames() {
+	g.watch.message = "Loading the live games..."
+	loaded := make(chan []live.Game, 1)
+	g.watch.loaded = loaded
+	go func() {
+		games, err := live.List(defaultconfig.LiveUrl)
+		if err != nil {
+			fmt.Println(err)
+		}
+		loaded <- games
+	}()
+}
+
+// updateWatch shows the live games once loaded, with a button for each
+func (g *game) updateWatch() {
+	select {
+	case games := <-g.watch.loaded:
+		g.watch.games = games[:min(len(games), maxLiveGames)]
+		g.watch.message = ""
+		if len(games) == 0 {
+			g.watch.message = "Nobody is playing now"
+		}
+		g.initiateLiveGameButtons()
+	default:
+	}
+
+	g.updateButtons(g.watch.buttons)
+	g.checkWatching()
+}
+
+func (g *game) initiateLiveGameButtons() {
+	g.watch.buttons = button.New()
+	for i, liveGame := range g.watch.games {
+		g.watch.buttons.New("Watch #"+strconv.Itoa(i+1), 520, 80+i*35, 100, 28, func() {
+			g.startWatching(liveGame.ID)
+		})
+	}
+	if len(g.watch.games) > 0 {
+		// The list has the best score first
+		top := g.watch.games[0].ID
+		g.watch.buttons.New("Watch the top player", 60, 400, 220, 28, func() {
+			g.startWatching(top)
+		})
+	}
+	g.watch.buttons.New("Refresh", 330, 400, 90, 28, g.refreshLiveGames)
+	g.watch.buttons.New("Back", 470, 400, 60, 28, func() {
+		g.stopWatching()
+		g.drawStatus = statusDrawIntro
+	})
+}
+
+// startWatching connects to the game in the background, the game is shown once its header arrives
+func (g *game) startWatching(id int) {
+	g.stopWatching()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	joined := make(chan live.Viewer, 1)
+	g.watch.cancel = cancel
+	g.watch.joined = joined
+	g.watch.message = "Connecting..."
+	go func() {
+		viewer, err := live.Watch(ctx, defaultconfig.LiveSocketUrl, id)
+		if err != nil {
+			fmt.Println(err)
+		}
+		joined <- viewer
+	}()
+}
+
+func (g *game) checkWatching() {
+	if g.watch.joined == nil {
+		return
+	}
+
+	select {
+	case viewer := <-g.watch.joined:
+		g.watch.joined = nil
+		if viewer == nil {
+			g.watch.message = "The game is not available"
+			return
+		}
+
+		g.watch.viewer = viewer
+		g.watch.inputs = []*scriptedControls{
+			{pressed: map[controls.Action]bool{}},
+			{pressed: map[controls.Action]bool{}},
+		}
+		g.watch.replay = g.newReplay(viewer.Header(), g.watch.inputs)
+		g.watch.events = nil
+		g.watch.ended = false
+		g.watch.message = ""
+		g.drawStatus = statusDrawWatching
+	default:
+	}
+}
+
+func (g *game) stopWatching() {
+	if g.watch.cancel != nil {
+		g.watch.cancel()
+		g.watch.cancel = nil
+	}
+	g.watch.joined = nil
+
+	if g.watch.viewer != nil {
+		g.watch.viewer.Close()
+		g.watch.viewer = nil
+	}
+	g.watch.replay = nil
+}
+
+// newReplay creates a game which plays the stream, it shares the images and the HUD but has no sound
+func (g *game) newReplay(header live.Header, inputs []*scriptedControls) *game {
+	r := &game{
+		controls:       inputs[0],
+		secondControls: inputs[1],
+		rnd:            newRand(header.Seed),
+		drawStatus:     statusDrawGame,
+		levels:         g.levels,
+		difficulty:     header.Difficulty,
+		coop:           header.Players == 2,
+		codex:          loadCodex(nil),
+		best:           loadBestScores(nil),
+		images:         g.images,
+		hud:            g.hud,
+	}
+	r.loadSprites()
+	r.init()
+
+	return r
+}
+
+// playEvent plays an event of a stream or a saved run with the inputs of the robots,
+// the draws render the frame unless catching up
+func (g *game) playEvent(inputs []*scriptedControls, event byte, render bool) {
+	switch event {
+	case eventDraw:
+		if !render {
+			g.drawPlayfield(nil)
+			return
+		}
+		g.renderFrame()
+	case eventRestart:
+		g.restartLevel()
+	default:
+		inputs[0].set(inputActions(netplay.Input(event & 7)))
+		inputs[1].set(inputActions(netplay.Input(event >> 3 & 7)))
+		g.handleShoot()
+		g.handleNavigation()
+	}
+}
+
+// drawWatching plays the stream up to the next frame, the last frame is shown while waiting for the stream
+func (g *game) drawWatching(screen *ebiten.Image) {
+	w := g.watch
+	events, ended := w.viewer.Events()
+	w.events = append(w.events, events...)
+	w.ended = w.ended || ended
+
+	skip := max(bytes.Count(w.events, []byte{eventDraw})-watchBuffer, 0)
+	for len(w.events) > 0 {
+		event := w.events[0]
+		w.events = w.events[1:]
+		if event == eventDraw && skip > 0 {
+			skip--
+			w.replay.playEvent(w.inputs, event, false)
+			continue
+		}
+
+		w.replay.playEvent(w.inputs, event, true)
+		if event == eventDraw {
+			break
+		}
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(g.images.frame, op)
+	if w.replay.gameStatus.loose {
+		screen.DrawImage(g.images.lostImage, op)
+	}
+
+	caption := "Watching " + w.viewer.Header().Name
+	if w.ended && len(w.events) == 0 {
+		caption += " - the game is over"
+	}
+	gametext.Draw(screen, caption, 10, 470)
+	g.watchingButtons.Render(screen)
+}
+
+func (g *game) drawWatch(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(g.images.titleImage, op)
+	screen.DrawImage(g.images.overlay, op)
+	gametext.Draw(screen, "LIVE GAMES", 270, 50)
+	for i, liveGame := range g.watch.games {
+		y := float64(100 + i*35)
+		gametext.Draw(screen, strconv.Itoa(i+1)+". "+liveGame.Name, 200, y)
+		gametext.Draw(screen, strconv.Itoa(liveGame.Score), 420, y)
+	}
+	gametext.Draw(screen, g.watch.message, 200, 380)
+	g.watch.buttons.Render(screen)
+}
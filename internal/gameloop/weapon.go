@@ -0,0 +1,209 @@
+package gameloop
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"spaceinvader/internal/defaultconfig"
+	"spaceinvader/internal/sprite"
+)
+
+const (
+	// weaponUpgradeKills is how many kills raise the weapon to the next level
+	weaponUpgradeKills = 12
+	// chargeFrames is how long fire is held for a charged shot
+	chargeFrames  = 60
+	chargedSize   = 36
+	chargedDamage = 3
+	doubleShotX   = 20
+	weaponSpreadX = 70
+)
+
+// weaponLevel is an upgrade of the player weapon, each level keeps the features of the one before
+type weaponLevel struct {
+	name string
+	// offsets turn the bullets of a shot sideways, one bullet each
+	offsets  []float64
+	piercing bool
+	charge   bool
+}
+
+var weaponLevels = []weaponLevel{
+	{"Single", []float64{0}, false, false},
+	{"Double", []float64{-doubleShotX, doubleShotX}, false, false},
+	{"Spread", []float64{-weaponSpreadX, 0, weaponSpreadX}, false, false},
+	{"Piercing", []float64{-weaponSpreadX, 0, weaponSpreadX}, true, false},
+	{"Charged", []float64{-weaponSpreadX, 0, weaponSpreadX}, true, true},
+}
+
+// weapon is the upgrade level of the player weapon and the state of its shots
+type weapon struct {
+	level int
+	// kills since the last upgrade
+	kills    int
+	cooldown int
+	// charge counts the frames fire is held, once the charged shot is unlocked
+	charge int
+	// shots are the bullets which go through the enemies, by bullet id
+	shots map[int]*shot
+}
+
+// shot is a piercing bullet, it hits each target once
+type shot struct {
+	damage int
+	hit    map[sprite.Sprite]bool
+}
+
+func newWeapon() weapon {
+	return weapon{shots: map[int]*shot{}}
+}
+
+func (p *player) weaponLevel() weaponLevel {
+	return weaponLevels[p.weapon.level]
+}
+
+// weaponReady reports if the cooldown is over and there is room for a bullet on the screen
+func (g *game) weaponReady(p *player) bool {
+	return p.weapon.cooldown == 0 && len(p.bullets) < g.difficulty.MaxBullets
+}
+
+// volley is the offsets of the bullets of a shot, when the screen can't take them all the middle ones are fired
+func (g *game) volley(p *player) []float64 {
+	offsets := slices.Clone(p.weaponLevel().offsets)
+	if g.powerUpActive(defaultconfig.FunctionCompute) {
+		offsets = append(offsets, -spreadShotX, spreadShotX)
+	}
+
+	free := max(g.difficulty.MaxBullets-len(p.bullets), 0)
+	if len(offsets) <= free {
+		return offsets
+	}
+	slices.SortStableFunc(offsets, func(a, b float64) int {
+		return cmp.Compare(math.Abs(a), math.Abs(b))
+	})
+
+	return offsets[:free]
+}
+
+// chargeWeapon charges while fire is held, the charged shot is fired when fire is released
+func (g *game) chargeWeapon(p *player, pressed bool) {
+	if !p.weaponLevel().charge {
+		p.weapon.charge = 0
+		return
+	}
+
+	if pressed {
+		p.weapon.charge = min(p.weapon.charge+1, chargeFrames)
+		return
+	}
+
+	if p.weapon.charge == chargeFrames {
+		g.fireCharged(p)
+	}
+	p.weapon.charge = 0
+}
+
+func (g *game) fireCharged(p *player) {
+	g.playLaunchSound()
+	g.gameStatus.bulletId++
+	g.shotFired()
+	bullet := sprite.New(
+		[]string{
+			"internal/images/Objects/star3.png",
+		},
+		chargedSize, chargedSize,
+		sprite.SpiteOptions{
+			Id:                g.gameStatus.bulletId,
+			AnimateOnMove:     true,
+			SoftX:             50,
+			SoftY:             50,
+			CollisionSprites:  g.bulletTargets(),
+			CollisionCallback: g.collisionCallback(p),
+		},
+	)
+
+	x := clamp(p.sprite.GetX()+(p.sprite.GetWidth()-chargedSize)/2, 0, ScreenW-chargedSize)
+	bullet.SetX(x)
+	bullet.SetY(p.sprite.GetY() - chargedSize)
+	bullet.Soft(true)
+	bullet.SetY(0)
+
+	p.bullets[g.gameStatus.bulletId] = bullet
+	p.weapon.shots[g.gameStatus.bulletId] = &shot{damage: chargedDamage, hit: map[sprite.Sprite]bool{}}
+}
+
+// weaponKill counts a kill towards the next upgrade
+func (g *game) weaponKill(p *player) {
+	if p.weapon.level == len(weaponLevels)-1 {
+		return
+	}
+
+	p.weapon.kills++
+	if p.weapon.kills < weaponUpgradeKills {
+		return
+	}
+
+	p.weapon.kills = 0
+	p.weapon.level++
+	g.addFloatingText("Weapon: "+p.weaponLevel().name, p.sprite.GetX()-40, p.sprite.GetY()-20, bonusColor)
+}
+
+// weaponDowngrade takes a level of the weapon after losing a life
+func (p *player) weaponDowngrade() {
+	p.weapon.level = max(p.weapon.level-1, 0)
+	p.weapon.kills = 0
+	p.weapon.charge = 0
+}
+
+// weaponProgress is the part of the kills done for the next upgrade, it's full at the last level
+func (p *player) weaponProgress() float64 {
+	if p.weapon.level == len(weaponLevels)-1 {
+		return 1
+	}
+
+	return float64(p.weapon.kills) / weaponUpgradeKills
+}
+
+// pierce reports if the bullet flies on after hitting the targets, and drops the targets it has already hit.
+// A piercing bullet counts as a single hit for the accuracy
+func (g *game) pierce(p *player, bullet sprite.Sprite, targets []sprite.Sprite) (bool, []sprite.Sprite) {
+	s := p.weapon.shots[bullet.Id()]
+	if s == nil {
+		return false, targets
+	}
+
+	first := len(s.hit) == 0
+	fresh := []sprite.Sprite{}
+	for _, target := range targets {
+		if !s.hit[target] {
+			s.hit[target] = true
+			fresh = append(fresh, target)
+		}
+	}
+
+	if first && len(fresh) > 0 {
+		g.shotHit()
+	}
+
+	return true, fresh
+}
+
+// shotDamage is how many hits the bullet deals to each target
+func (p *player) shotDamage(bullet sprite.Sprite) int {
+	if s := p.weapon.shots[bullet.Id()]; s != nil {
+		return s.damage
+	}
+
+	return 1
+}
+
+// removeBullet closes the bullet, a bullet missing all the targets breaks the kill chain
+func (g *game) removeBullet(p *player, bullet sprite.Sprite) {
+	if s := p.weapon.shots[bullet.Id()]; s == nil || len(s.hit) == 0 {
+		g.breakChain()
+	}
+
+	bullet.Close()
+	delete(p.bullets, bullet.Id())
+	delete(p.weapon.shots, bullet.Id())
+}
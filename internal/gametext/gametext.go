@@ -48,6 +48,11 @@ func DrawWithColor(screen *ebiten.Image, drawText string, x, y float64, textColo
 	return calcTextWidth(drawText)
 }
 
+// Width is the width of the text as drawn
+func Width(text string) float64 {
+	return calcTextWidth(text)
+}
+
 func calcTextWidth(text string) float64 {
 	bounds, _ := font.BoundString(fontFace, text)
 
@@ -0,0 +1,219 @@
+// Package hud draws the heads-up display on top of the playfield
+package hud
+
+import (
+	"fmt"
+	"image/color"
+	"spaceinvader/internal/gametext"
+	"spaceinvader/internal/sprite"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	lifeIconSize = 20
+	// maxLifeIcons are drawn, more lives are shown as a number
+	maxLifeIcons    = 6
+	weaponBarWidth  = 60
+	weaponBarHeight = 4
+	// The power-up timers wrap between the lives of the first player and the wave progress
+	powerUpsLeft  = 200
+	powerUpsRight = 480
+	powerUpsRow   = 24
+)
+
+// lifeIconImages are the robots of the players, in the same order
+var lifeIconImages = []string{
+	"internal/images/robot-fighter.png",
+	"internal/images/robot-fighter2.png",
+}
+
+// playerBlocks are where the lives, the weapon and the score of each player are drawn
+var playerBlocks = []struct {
+	x, livesY, weaponY, scoreY float64
+}{
+	{x: 10, livesY: 26, weaponY: 66, scoreY: 86},
+	{x: 490, livesY: 44, weaponY: 86, scoreY: 106},
+}
+
+var (
+	labelTextColor = color.RGBA{20, 20, 40, 255}
+	progressBack   = color.RGBA{40, 40, 80, 255}
+	progressColor  = color.RGBA{255, 165, 0, 255}
+	chargeColor    = color.RGBA{120, 200, 255, 255}
+	glyphColor     = color.RGBA{230, 230, 230, 255}
+	padColor       = color.RGBA{60, 60, 60, 60}
+	toastColor     = color.RGBA{20, 20, 40, 220}
+	toastTitle     = color.RGBA{255, 220, 60, 255}
+)
+
+// PowerUp is an active power-up with its seconds left
+type PowerUp struct {
+	Label   string
+	Color   color.RGBA
+	Seconds int
+}
+
+// Weapon is the upgrade of the player weapon
+type Weapon struct {
+	Name string
+	// Progress towards the next upgrade from 0 to 1
+	Progress float64
+	// Charge of the charged shot from 0 to 1, the bar is hidden when it's zero
+	Charge float64
+}
+
+// Player is the part of the HUD of each player
+type Player struct {
+	Name   string
+	Score  int
+	Lives  int
+	Weapon Weapon
+}
+
+// Status is what the HUD shows in a frame
+type Status struct {
+	Score int
+	// Multiplier is shown when greater than one
+	Multiplier int
+	LocalBest  int
+	// GlobalBest is the top of the leaderboard, it's hidden when it's not loaded
+	GlobalBest int
+	Level      string
+	PowerUps   []PowerUp
+	// Progress of the wave from 0 to 1
+	Progress float64
+	// Players have their own score only when there are two of them
+	Players []Player
+}
+
+type HUD interface {
+	Draw(screen *ebiten.Image, status Status)
+}
+
+type hud struct {
+	lifeIcons []*ebiten.Image
+}
+
+func New() HUD {
+	h := &hud{}
+	for _, path := range lifeIconImages {
+		lifeIcon, _, _, err := sprite.RescaleImageToFit(path, lifeIconSize, lifeIconSize)
+		if err != nil {
+			fmt.Println(err)
+		}
+		h.lifeIcons = append(h.lifeIcons, lifeIcon)
+	}
+
+	return h
+}
+
+func (h *hud) Draw(screen *ebiten.Image, status Status) {
+	score := "Score " + strconv.Itoa(status.Score)
+	if status.Multiplier > 1 {
+		score += " x" + strconv.Itoa(status.Multiplier)
+	}
+	gametext.Draw(screen, score, 10, 20)
+	gametext.Draw(screen, "Best "+strconv.Itoa(max(status.LocalBest, status.Score)), 200, 20)
+	if status.GlobalBest > 0 {
+		gametext.Draw(screen, "World "+strconv.Itoa(status.GlobalBest), 340, 20)
+	}
+	gametext.Draw(screen, status.Level, 490, 20)
+
+	x, y := float64(powerUpsLeft), 28.0
+	for _, p := range status.PowerUps {
+		seconds := strconv.Itoa(p.Seconds)
+		width := 50 + gametext.Width(seconds)
+		if x+width > powerUpsRight && x > powerUpsLeft {
+			x, y = powerUpsLeft, y+powerUpsRow
+		}
+		DrawLabel(screen, p.Label, p.Color, x, y)
+		gametext.Draw(screen, seconds, x+50, y+16)
+		x += width + 15
+	}
+
+	vector.DrawFilledRect(screen, 490, 32, 140, 8, progressBack, false)
+	vector.DrawFilledRect(screen, 490, 32, float32(140*min(max(status.Progress, 0), 1)), 8, progressColor, false)
+
+	for i, player := range status.Players[:min(len(status.Players), len(playerBlocks))] {
+		block := playerBlocks[i]
+		h.drawLives(screen, i, player.Lives, block.x, block.livesY)
+		drawWeapon(screen, player.Weapon, block.x, block.weaponY)
+		if len(status.Players) > 1 {
+			gametext.Draw(screen, player.Name+" "+strconv.Itoa(player.Score), block.x, block.scoreY)
+		}
+	}
+}
+
+// drawWeapon shows the weapon with bars for the next upgrade and the charge
+func drawWeapon(screen *ebiten.Image, weapon Weapon, x, y float64) {
+	barX := x + gametext.Draw(screen, weapon.Name, x, y) + 8
+	drawBar(screen, barX, y-12, weapon.Progress, progressColor)
+	if weapon.Charge > 0 {
+		drawBar(screen, barX, y-6, weapon.Charge, chargeColor)
+	}
+}
+
+func drawBar(screen *ebiten.Image, x, y, value float64, barColor color.RGBA) {
+	vector.DrawFilledRect(screen, float32(x), float32(y), weaponBarWidth, weaponBarHeight, progressBack, false)
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(weaponBarWidth*min(max(value, 0), 1)), weaponBarHeight, barColor, false)
+}
+
+// drawLives shows the lives as icons of the robot of the player, the top left of the first icon is at x, y
+func (h *hud) drawLives(screen *ebiten.Image, player, lives int, x, y float64) {
+	lifeIcon := h.lifeIcons[player]
+	for i := range min(lives, maxLifeIcons) {
+		if lifeIcon == nil {
+			break
+		}
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(x+float64(i*(lifeIconSize+4)), y)
+		screen.DrawImage(lifeIcon, op)
+	}
+
+	if lives > maxLifeIcons {
+		gametext.Draw(screen, "+"+strconv.Itoa(lives-maxLifeIcons), x+float64(maxLifeIcons*(lifeIconSize+4)), y+18)
+	}
+}
+
+// DrawLabel draws a short caption on a colored box, as the power-up icons
+func DrawLabel(screen *ebiten.Image, label string, background color.RGBA, x, y float64) {
+	vector.DrawFilledRect(screen, float32(x), float32(y), 44, 20, background, false)
+	gametext.DrawWithColor(screen, label, x+4, y+16, labelTextColor)
+}
+
+// DrawGlyph draws a key or a gamepad button of a prompt followed by its caption, it returns the width drawn.
+// The gamepad buttons are round, the keys are square
+func DrawGlyph(screen *ebiten.Image, glyph, caption string, round bool, x, y float64) float64 {
+	w := float32(max(gametext.Width(glyph)+10, 20))
+	if round {
+		// A pill, the ends are half circles
+		vector.DrawFilledCircle(screen, float32(x)+10, float32(y)+10, 10, glyphColor, true)
+		vector.DrawFilledCircle(screen, float32(x)+w-10, float32(y)+10, 10, glyphColor, true)
+		vector.DrawFilledRect(screen, float32(x)+10, float32(y), w-20, 20, glyphColor, false)
+	} else {
+		vector.DrawFilledRect(screen, float32(x), float32(y), w, 20, glyphColor, false)
+	}
+	gametext.DrawWithColor(screen, glyph, x+(float64(w)-gametext.Width(glyph))/2, y+16, labelTextColor)
+
+	return float64(w) + 6 + gametext.Draw(screen, caption, x+float64(w)+6, y+16) + 14
+}
+
+// DrawPad draws a touch pad, see-through so the game shows behind it
+func DrawPad(screen *ebiten.Image, caption string, x, y, w, h float64) {
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), padColor, false)
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 2, glyphColor, false)
+	gametext.Draw(screen, caption, x+(w-gametext.Width(caption))/2, y+h/2+6)
+}
+
+// DrawToast draws a short notice with a title on a dark box, as the achievements unlocked
+func DrawToast(screen *ebiten.Image, title, text string, x, y float64) {
+	w := max(gametext.Width(title), gametext.Width(text)) + 20
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), 56, toastColor, false)
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), 56, 2, toastTitle, false)
+	gametext.DrawWithColor(screen, title, x+10, y+22, toastTitle)
+	gametext.Draw(screen, text, x+10, y+46)
+}
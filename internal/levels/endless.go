@@ -0,0 +1,132 @@
+package levels
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// shapes decide if a cell of the formation is filled
+var shapes = []func(column, row, columns, rows int, rnd *rand.Rand) bool{
+	// Full grid
+	func(_, _, _, _ int, _ *rand.Rand) bool {
+		return true
+	},
+	// Checkerboard
+	func(column, row, _, _ int, _ *rand.Rand) bool {
+		return (column+row)%2 == 0
+	},
+	// Wedge widening towards the ground
+	func(column, row, columns, _ int, _ *rand.Rand) bool {
+		return abs(2*column-(columns-1)) <= 2*row+1
+	},
+	// Wedge narrowing towards the ground
+	func(column, row, columns, rows int, _ *rand.Rand) bool {
+		return abs(2*column-(columns-1)) <= 2*(rows-1-row)+1
+	},
+	// Pillars
+	func(column, _, _, _ int, _ *rand.Rand) bool {
+		return column%2 == 0
+	},
+	// Scattered
+	func(_, _, _, _ int, rnd *rand.Rand) bool {
+		return rnd.IntN(3) != 0
+	},
+}
+
+// bossWaves is how often a boss wave comes in the endless mode
+const bossWaves = 5
+
+// Endless generates a wave after the last level, wave starts from 1.
+// The pace is based on the last formation level and increases with every wave,
+// every few waves the last boss returns with more health
+func (l Levels) Endless(wave int, rnd *rand.Rand) Level {
+	if boss, ok := l.last(func(level Level) bool { return level.Boss != nil }); ok && wave%bossWaves == 0 {
+		boss.Name = fmt.Sprintf("Endless wave %d", wave)
+		scaled := *boss.Boss
+		scaled.Health += scaled.Health * wave / (2 * bossWaves)
+		boss.Boss = &scaled
+
+		return boss
+	}
+
+	last, _ := l.last(func(level Level) bool { return len(level.Rows) > 0 })
+	enemies := slices.Sorted(maps.Keys(l.Enemies))
+
+	columns := 5 + rnd.IntN(3)
+	rows := 3 + rnd.IntN(2)
+	level := Level{
+		Name:       fmt.Sprintf("Endless wave %d", wave),
+		Background: last.Background,
+		Formation: Formation{
+			Columns:  columns,
+			Left:     1,
+			Top:      45,
+			SpacingX: 70,
+			SpacingY: 50,
+			Pattern:  pattern(columns, rows, rnd),
+		},
+		Speed:          last.Speed + 0.25*float64(wave),
+		SpeedUp:        last.SpeedUp + 0.02*float64(wave),
+		Descent:        last.Descent + float64(min(wave, 8)),
+		BombSpeed:      last.BombSpeed + 2*float64(wave),
+		BombChance:     last.BombChance,
+		FirstBombDelay: max(last.FirstBombDelay-5*wave, 30),
+		BombDelayMin:   last.BombDelayMin,
+		BombDelayMax:   max(last.BombDelayMax-5*wave, last.BombDelayMin+10),
+		Shields:        last.Shields,
+		Projectiles:    last.Projectiles,
+	}
+
+	for range rows {
+		level.Rows = append(level.Rows, enemies[rnd.IntN(len(enemies))])
+	}
+
+	return level
+}
+
+// last returns the last level matching the condition
+func (l Levels) last(match func(Level) bool) (Level, bool) {
+	for i := len(l.Levels) - 1; i >= 0; i-- {
+		if match(l.Levels[i]) {
+			return l.Levels[i], true
+		}
+	}
+
+	return Level{}, false
+}
+
+func pattern(columns, rows int, rnd *rand.Rand) []string {
+	shape := shapes[rnd.IntN(len(shapes))]
+	lines := make([]string, rows)
+	ships := 0
+	for row := range rows {
+		var line strings.Builder
+		for column := range columns {
+			if shape(column, row, columns, rows, rnd) {
+				line.WriteByte('x')
+				ships++
+				continue
+			}
+			line.WriteByte('.')
+		}
+		lines[row] = line.String()
+	}
+
+	// A random shape could leave the formation empty
+	if ships == 0 {
+		return nil
+	}
+
+	return lines
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
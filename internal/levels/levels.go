@@ -0,0 +1,407 @@
+// Package levels describes the formation, enemies and pace of each level
+package levels
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//go:embed levels.json
+var defaultLevels []byte
+
+// Formation is the grid layout of the ufos at the start of the level
+type Formation struct {
+	Columns  int     `json:"columns"`
+	Left     float64 `json:"left"`
+	Top      float64 `json:"top"`
+	SpacingX float64 `json:"spacingX"`
+	SpacingY float64 `json:"spacingY"`
+	// Pattern is optional, a string per row where '.' leaves the cell empty, any other character places a ship
+	Pattern []string `json:"pattern"`
+}
+
+// Filled reports if a ship is placed to the cell of the formation
+func (f Formation) Filled(column, row int) bool {
+	if len(f.Pattern) == 0 {
+		return true
+	}
+
+	return f.Pattern[row][column] != '.'
+}
+
+// DefaultShieldShape is the classic bunker used when the level doesn't set a shape
+var DefaultShieldShape = []string{
+	"..xxxxxxxx..",
+	".xxxxxxxxxx.",
+	"xxxxxxxxxxxx",
+	"xxxxxxxxxxxx",
+	"xxxxxxxxxxxx",
+	"xxxx....xxxx",
+	"xxx......xxx",
+	"xxx......xxx",
+}
+
+// Shields are the destructible bunkers between the player and the formation
+type Shields struct {
+	Count int     `json:"count"`
+	Y     float64 `json:"y"`
+	// Shape is optional, a string per row of cells where '.' leaves the cell empty
+	Shape []string `json:"shape"`
+}
+
+// Cells returns the shape of a shield
+func (s Shields) Cells() []string {
+	if len(s.Shape) == 0 {
+		return DefaultShieldShape
+	}
+
+	return s.Shape
+}
+
+// Enemy behaviours, the ships without a behaviour stay in the formation
+const (
+	// BehaviorDiver breaks the formation to dive at the player
+	BehaviorDiver = "diver"
+	// BehaviorShooter aims its bombs at the player
+	BehaviorShooter = "shooter"
+	// BehaviorArmoured takes more than one hit
+	BehaviorArmoured = "armoured"
+)
+
+// Enemy projectiles, the ships without a projectile drop bombs
+const (
+	// ProjectileBomb falls to the ground speeding up
+	ProjectileBomb = "bomb"
+	// ProjectileAimed flies straight at the player
+	ProjectileAimed = "aimed"
+	// ProjectileSpread is a volley of shots fanning out around the player
+	ProjectileSpread = "spread"
+	// ProjectileHoming is a slow missile turning towards the player for a while
+	ProjectileHoming = "homing"
+	// ProjectileLaser is a vertical beam below the ship, a warning line shows where it fires first
+	ProjectileLaser = "laser"
+)
+
+// Enemy is a ship type which can be used in the formation rows
+type Enemy struct {
+	Images []string `json:"images"`
+	// Points are scored when the ship is destroyed
+	Points   int    `json:"points"`
+	Behavior string `json:"behavior"`
+	// Health is the number of hits an armoured ship takes, the others are destroyed by the first hit
+	Health int `json:"health"`
+	// DamageImages are the images of an armoured ship after each hit
+	DamageImages [][]string `json:"damageImages"`
+	// Projectile is optional, one of bomb, aimed, spread, homing and laser
+	Projectile string `json:"projectile"`
+}
+
+// Boss attacks
+const (
+	AttackSpread  = "spread"
+	AttackLaser   = "laser"
+	AttackMinions = "minions"
+)
+
+// Boss is a large multi-hit enemy ending a level set
+type Boss struct {
+	Images []string `json:"images"`
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Health int      `json:"health"`
+	Speed  float64  `json:"speed"`
+	// AttackDelay is the number of frames between two attacks
+	AttackDelay int `json:"attackDelay"`
+	// Attacks are picked at random, any of spread, laser and minions
+	Attacks []string `json:"attacks"`
+	// Minion is the enemy type summoned by the minions attack
+	Minion string `json:"minion"`
+	// Points are scored when the boss is destroyed
+	Points int `json:"points"`
+}
+
+// Level describes one wave of the invasion
+type Level struct {
+	Name       string    `json:"name"`
+	Background string    `json:"background"`
+	Formation  Formation `json:"formation"`
+	// Rows contains the enemy type of each formation row, top to bottom
+	Rows    []string `json:"rows"`
+	Speed   float64  `json:"speed"`
+	SpeedUp float64  `json:"speedUp"`
+	Descent float64  `json:"descent"`
+	// BombSpeed is how fast the bombs accelerate towards the ground
+	BombSpeed float64 `json:"bombSpeed"`
+	// BombChance is one in how many ufos are picked to drop the next bomb
+	BombChance     int `json:"bombChance"`
+	FirstBombDelay int `json:"firstBombDelay"`
+	BombDelayMin   int `json:"bombDelayMin"`
+	BombDelayMax   int `json:"bombDelayMax"`
+	// Shields are optional, there are no bunkers when the count is zero
+	Shields Shields `json:"shields"`
+	// Projectiles optionally replace the projectile of enemy types in this level, by enemy name
+	Projectiles map[string]string `json:"projectiles"`
+	// Boss is optional, the level is cleared when both the formation and the boss are destroyed
+	Boss *Boss `json:"boss"`
+}
+
+// Levels is the content of a level file
+type Levels struct {
+	Enemies map[string]Enemy `json:"enemies"`
+	Levels  []Level          `json:"levels"`
+}
+
+// Default returns the levels built into the game
+func Default() Levels {
+	levels, err := Parse(defaultLevels)
+	if err != nil {
+		panic(err)
+	}
+
+	return levels
+}
+
+// Load reads and validates a level file
+func Load(r io.Reader) (Levels, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Levels{}, fmt.Errorf("cannot read levels: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse decodes and validates level data
+func Parse(data []byte) (Levels, error) {
+	var levels Levels
+	if err := json.Unmarshal(data, &levels); err != nil {
+		return Levels{}, fmt.Errorf("cannot decode levels: %w", err)
+	}
+
+	if err := levels.validate(); err != nil {
+		return Levels{}, err
+	}
+
+	return levels, nil
+}
+
+func (l Levels) validate() error {
+	if len(l.Levels) == 0 {
+		return errors.New("no levels defined")
+	}
+
+	// The endless waves are based on the last formation
+	if _, ok := l.last(func(level Level) bool { return len(level.Rows) > 0 }); !ok {
+		return errors.New("at least one level requires a formation")
+	}
+
+	for name, enemy := range l.Enemies {
+		if len(enemy.Images) == 0 {
+			return fmt.Errorf("enemy %s has no images", name)
+		}
+
+		if enemy.Points < 0 {
+			return fmt.Errorf("enemy %s has negative points", name)
+		}
+
+		if err := enemy.validateBehavior(); err != nil {
+			return fmt.Errorf("enemy %s: %w", name, err)
+		}
+
+		if err := validateProjectile(enemy.Projectile); err != nil {
+			return fmt.Errorf("enemy %s: %w", name, err)
+		}
+	}
+
+	for i, level := range l.Levels {
+		if err := level.validate(l.Enemies); err != nil {
+			return fmt.Errorf("level %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (l Level) validateFormation(enemies map[string]Enemy) error {
+	if l.Formation.Columns <= 0 {
+		return errors.New("formation requires at least one column")
+	}
+
+	if len(l.Rows) == 0 {
+		return errors.New("formation requires at least one row")
+	}
+
+	if err := l.Formation.validatePattern(len(l.Rows)); err != nil {
+		return err
+	}
+
+	for _, row := range l.Rows {
+		if _, ok := enemies[row]; !ok {
+			return fmt.Errorf("unknown enemy %s", row)
+		}
+	}
+
+	if l.Speed <= 0 {
+		return errors.New("speed must be positive")
+	}
+
+	return nil
+}
+
+func (b *Boss) validate(enemies map[string]Enemy) error {
+	if len(b.Images) == 0 {
+		return errors.New("no images")
+	}
+
+	if b.Width <= 0 || b.Height <= 0 {
+		return errors.New("size must be positive")
+	}
+
+	if b.Health <= 0 || b.Speed <= 0 || b.AttackDelay <= 0 {
+		return errors.New("health, speed and attack delay must be positive")
+	}
+
+	if b.Points < 0 {
+		return errors.New("points can't be negative")
+	}
+
+	if len(b.Attacks) == 0 {
+		return errors.New("no attacks")
+	}
+
+	for _, attack := range b.Attacks {
+		switch attack {
+		case AttackSpread, AttackLaser:
+		case AttackMinions:
+			if _, ok := enemies[b.Minion]; !ok {
+				return fmt.Errorf("unknown minion %s", b.Minion)
+			}
+		default:
+			return fmt.Errorf("unknown attack %s", attack)
+		}
+	}
+
+	return nil
+}
+
+func (e Enemy) validateBehavior() error {
+	switch e.Behavior {
+	case "", BehaviorDiver, BehaviorShooter:
+	case BehaviorArmoured:
+		if e.Health < 2 {
+			return errors.New("an armoured ship requires at least 2 health")
+		}
+	default:
+		return fmt.Errorf("unknown behavior %s", e.Behavior)
+	}
+
+	for _, images := range e.DamageImages {
+		if len(images) == 0 {
+			return errors.New("damage images require at least one image per hit")
+		}
+	}
+
+	return nil
+}
+
+func validateProjectile(projectile string) error {
+	switch projectile {
+	case "", ProjectileBomb, ProjectileAimed, ProjectileSpread, ProjectileHoming, ProjectileLaser:
+		return nil
+	default:
+		return fmt.Errorf("unknown projectile %s", projectile)
+	}
+}
+
+func (s Shields) validate() error {
+	if s.Count < 0 {
+		return errors.New("count can't be negative")
+	}
+
+	// A missing shape is the default one, but an empty one leaves nothing to draw
+	if s.Shape != nil && len(s.Shape) == 0 {
+		return errors.New("shape requires a line")
+	}
+
+	for _, line := range s.Shape {
+		if len(line) == 0 {
+			return errors.New("shape lines require a cell")
+		}
+		if len(line) != len(s.Shape[0]) {
+			return errors.New("shape lines require the same length")
+		}
+	}
+
+	return nil
+}
+
+func (f Formation) validatePattern(rows int) error {
+	if len(f.Pattern) == 0 {
+		return nil
+	}
+
+	if len(f.Pattern) != rows {
+		return errors.New("formation pattern requires a line per row")
+	}
+
+	ships := 0
+	for _, line := range f.Pattern {
+		if len(line) != f.Columns {
+			return errors.New("formation pattern lines require a character per column")
+		}
+		ships += f.Columns - strings.Count(line, ".")
+	}
+
+	if ships == 0 {
+		return errors.New("formation pattern has no ships")
+	}
+
+	return nil
+}
+
+func (l Level) validate(enemies map[string]Enemy) error {
+	if l.Boss != nil {
+		if err := l.Boss.validate(enemies); err != nil {
+			return fmt.Errorf("boss: %w", err)
+		}
+	}
+
+	// A boss level doesn't require a formation
+	if l.Boss == nil || len(l.Rows) > 0 {
+		if err := l.validateFormation(enemies); err != nil {
+			return err
+		}
+	}
+
+	if err := l.Shields.validate(); err != nil {
+		return fmt.Errorf("shields: %w", err)
+	}
+
+	for name, projectile := range l.Projectiles {
+		if _, ok := enemies[name]; !ok {
+			return fmt.Errorf("projectiles: unknown enemy %s", name)
+		}
+
+		if err := validateProjectile(projectile); err != nil {
+			return fmt.Errorf("projectiles: %w", err)
+		}
+	}
+
+	if l.BombSpeed <= 0 {
+		return errors.New("bomb speed must be positive")
+	}
+
+	if l.BombChance <= 0 {
+		return errors.New("bomb chance must be positive")
+	}
+
+	if l.BombDelayMin < 0 || l.BombDelayMax <= l.BombDelayMin {
+		return errors.New("bomb delay max must be greater than min")
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package levels
+
+import "testing"
+
+func TestShieldsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		shields Shields
+		valid   bool
+	}{
+		{name: "default shape", shields: Shields{Count: 4, Y: 350}, valid: true},
+		{name: "custom shape", shields: Shields{Count: 2, Y: 350, Shape: []string{".##.", "####"}}, valid: true},
+		{name: "negative count", shields: Shields{Count: -1, Y: 350}},
+		{name: "empty shape", shields: Shields{Count: 4, Y: 350, Shape: []string{}}},
+		{name: "empty line", shields: Shields{Count: 4, Y: 350, Shape: []string{""}}},
+		{name: "lines of other lengths", shields: Shields{Count: 4, Y: 350, Shape: []string{"###", "##"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.shields.validate(); (err == nil) != test.valid {
+				t.Fatalf("validate() = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Package live streams the running games to the spectator server, and watches them from there.
+// The games are deterministic, so the stream is the seed and the inputs, not the state
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"spaceinvader/internal/difficulty"
+	"strconv"
+	"sync"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// Message types
+const (
+	// TypeHeader opens the stream, it's sent once by the game and first to the viewers
+	TypeHeader = "header"
+	TypeEvents = "events"
+	// TypeEnd is sent to the viewers when the game is over
+	TypeEnd = "end"
+)
+
+const (
+	sendInterval = 500 * time.Millisecond
+	writeTimeout = 5 * time.Second
+)
+
+// Header has what the viewers need to play the same game
+type Header struct {
+	Name       string
+	Seed       uint64
+	Difficulty difficulty.Difficulty
+	Players    int
+}
+
+// Message is sent as JSON, Events are the game events since the previous message
+type Message struct {
+	Type   string  `json:"type"`
+	Header *Header `json:"header,omitempty"`
+	Events []byte  `json:"events,omitempty"`
+	Score  int     `json:"score,omitempty"`
+}
+
+// Game is a live game in the list of the spectator server
+type Game struct {
+	ID    int
+	Name  string
+	Score int
+}
+
+// Broadcaster streams a game, the events are sent in the background
+type Broadcaster interface {
+	// Add queues the events with the current score, it never blocks the game
+	Add(events []byte, score int)
+	Close()
+}
+
+type broadcaster struct {
+	mu      sync.Mutex
+	pending []byte
+	score   int
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Broadcast starts streaming a game to the server, the stream is dropped if the server can't be reached
+func Broadcast(socketUrl string, header Header) Broadcaster {
+	b := &broadcaster{done: make(chan struct{})}
+	go b.run(socketUrl+"broadcast", header)
+
+	return b
+}
+
+func (b *broadcaster) Add(events []byte, score int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, events...)
+	b.score = score
+}
+
+func (b *broadcaster) Close() {
+	b.once.Do(func() {
+		close(b.done)
+	})
+}
+
+func (b *broadcaster) run(socketUrl string, header Header) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, socketUrl, nil)
+	if err != nil {
+		fmt.Println("cannot stream the game:", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	if err := write(ctx, conn, Message{Type: TypeHeader, Header: &header}); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ticker := time.NewTicker(sendInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.done:
+			// The last events are sent, so the viewers see the end of the game
+			_ = b.flush(ctx, conn)
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+
+		if err := b.flush(ctx, conn); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+}
+
+func (b *broadcaster) flush(ctx context.Context, conn *websocket.Conn) error {
+	b.mu.Lock()
+	events, score := b.pending, b.score
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	return write(ctx, conn, Message{Type: TypeEvents, Events: events, Score: score})
+}
+
+func write(ctx context.Context, conn *websocket.Conn, message Message) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	if err := wsjson.Write(ctx, conn, message); err != nil {
+		return fmt.Errorf("cannot send to the spectator server: %w", err)
+	}
+
+	return nil
+}
+
+// Viewer receives a live game, from its start
+type Viewer interface {
+	Header() Header
+	// Events returns the events received since the previous call, and if the game is over
+	Events() ([]byte, bool)
+	Close()
+}
+
+type viewer struct {
+	conn   *websocket.Conn
+	cancel context.CancelFunc
+	header Header
+
+	mu     sync.Mutex
+	events []byte
+	ended  bool
+}
+
+// Watch connects to a live game and waits for its header
+func Watch(ctx context.Context, socketUrl string, id int) (Viewer, error) {
+	conn, _, err := websocket.Dial(ctx, socketUrl+"watch?id="+strconv.Itoa(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the spectator server: %w", err)
+	}
+
+	var message Message
+	if err := wsjson.Read(ctx, conn, &message); err != nil {
+		conn.CloseNow()
+		return nil, fmt.Errorf("cannot watch the game: %w", err)
+	}
+
+	if message.Type != TypeHeader || message.Header == nil {
+		conn.CloseNow()
+		return nil, fmt.Errorf("unexpected message: %s", message.Type)
+	}
+
+	v := &viewer{conn: conn, header: *message.Header}
+	readCtx, cancel := context.WithCancel(context.Background())
+	v.cancel = cancel
+	go v.read(readCtx)
+
+	return v, nil
+}
+
+func (v *viewer) Header() Header {
+	return v.header
+}
+
+func (v *viewer) Events() ([]byte, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	events := v.events
+	v.events = nil
+
+	return events, v.ended && len(events) == 0
+}
+
+func (v *viewer) Close() {
+	v.cancel()
+	v.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func (v *viewer) read(ctx context.Context) {
+	for {
+		var message Message
+		err := wsjson.Read(ctx, v.conn, &message)
+
+		v.mu.Lock()
+		if err != nil || message.Type == TypeEnd {
+			v.ended = true
+			v.mu.Unlock()
+			return
+		}
+		v.events = append(v.events, message.Events...)
+		v.mu.Unlock()
+	}
+}
+
+// List returns the live games, the best score first
+func List(apiUrl string) ([]Game, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(apiUrl + "games")
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the live games: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var games []Game
+	if err := json.NewDecoder(resp.Body).Decode(&games); err != nil {
+		return nil, fmt.Errorf("cannot decode the live games: %w", err)
+	}
+
+	return games, nil
+}
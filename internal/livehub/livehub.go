@@ -0,0 +1,184 @@
+//go:build !js
+
+// Package livehub is the spectator server, it keeps the streams of the live games and sends them to the viewers
+package livehub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"spaceinvader/internal/live"
+	"strconv"
+	"sync"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// stream is a live game, the events are kept from the start so a viewer can join any time
+type stream struct {
+	id     int
+	header live.Header
+	events []byte
+	score  int
+	ended  bool
+	// changed is closed and replaced on every change, the viewers wait on it
+	changed chan struct{}
+}
+
+type hub struct {
+	mu      sync.Mutex
+	lastId  int
+	streams map[int]*stream
+	// origins are the host patterns of the pages the games are served from, the desktop client sends no origin
+	origins []string
+}
+
+// New returns the handler of the spectator server, it serves games, broadcast and watch under its path
+func New(origins []string) http.Handler {
+	h := &hub{streams: map[int]*stream{}, origins: origins}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /games", h.games)
+	mux.HandleFunc("/broadcast", h.broadcast)
+	mux.HandleFunc("/watch", h.watch)
+
+	return mux
+}
+
+// games lists the live games, the best score first so the top player is easy to find
+func (h *hub) games(w http.ResponseWriter, _ *http.Request) {
+	h.mu.Lock()
+	games := []live.Game{}
+	for _, s := range h.streams {
+		games = append(games, live.Game{ID: s.id, Name: s.header.Name, Score: s.score})
+	}
+	h.mu.Unlock()
+
+	slices.SortFunc(games, func(a, b live.Game) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+
+		return a.ID - b.ID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	// The game is served from another origin
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if err := json.NewEncoder(w).Encode(games); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// broadcast receives the stream of a game until the game closes it
+func (h *hub) broadcast(w http.ResponseWriter, req *http.Request) {
+	conn, err := websocket.Accept(w, req, &websocket.AcceptOptions{OriginPatterns: h.origins})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.CloseNow()
+
+	ctx := context.Background()
+	var message live.Message
+	if err := wsjson.Read(ctx, conn, &message); err != nil || message.Type != live.TypeHeader || message.Header == nil {
+		fmt.Println("the stream has no header:", err)
+		return
+	}
+
+	h.mu.Lock()
+	h.lastId++
+	s := &stream{id: h.lastId, header: *message.Header, changed: make(chan struct{})}
+	h.streams[s.id] = s
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		s.ended = true
+		h.notify(s)
+		delete(h.streams, s.id)
+		h.mu.Unlock()
+	}()
+
+	for {
+		var message live.Message
+		if err := wsjson.Read(ctx, conn, &message); err != nil {
+			return
+		}
+
+		if message.Type != live.TypeEvents {
+			continue
+		}
+
+		h.mu.Lock()
+		s.events = append(s.events, message.Events...)
+		s.score = message.Score
+		h.notify(s)
+		h.mu.Unlock()
+	}
+}
+
+// notify wakes the viewers of the stream, it must be called with the lock held
+func (h *hub) notify(s *stream) {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// watch sends the header and all the events of the game, then the new events as they arrive
+func (h *hub) watch(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.Atoi(req.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	s, ok := h.streams[id]
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "the game is over", http.StatusNotFound)
+		return
+	}
+
+	conn, err := websocket.Accept(w, req, &websocket.AcceptOptions{OriginPatterns: h.origins})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// The viewer only receives, reading detects when it leaves
+	ctx := conn.CloseRead(context.Background())
+	if err := wsjson.Write(ctx, conn, live.Message{Type: live.TypeHeader, Header: &s.header}); err != nil {
+		return
+	}
+
+	sent := 0
+	for {
+		h.mu.Lock()
+		events, ended, changed := s.events[sent:], s.ended, s.changed
+		h.mu.Unlock()
+
+		if len(events) > 0 {
+			if err := wsjson.Write(ctx, conn, live.Message{Type: live.TypeEvents, Events: events}); err != nil {
+				return
+			}
+			sent += len(events)
+		}
+
+		if ended {
+			_ = wsjson.Write(ctx, conn, live.Message{Type: live.TypeEnd})
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package netplay connects two games through the relay server, they play in lockstep by exchanging their inputs
+package netplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// Message types
+const (
+	// TypeStart is sent by the relay to both games once they are paired
+	TypeStart = "start"
+	TypeInput = "input"
+)
+
+const writeTimeout = 5 * time.Second
+
+// Message is sent as JSON, the relay sends the start and forwards the inputs to the other game
+type Message struct {
+	Type string `json:"type"`
+	// Seed and Player are only set in the start message, the player is 0 or 1
+	Seed   uint64 `json:"seed,omitempty"`
+	Player int    `json:"player,omitempty"`
+	Frame  int    `json:"frame,omitempty"`
+	Input  Input  `json:"input,omitempty"`
+}
+
+// Input is a bit mask of the actions held down in a frame
+type Input uint8
+
+// ErrClosed is returned once the other game or the relay is gone
+var ErrClosed = errors.New("connection closed")
+
+type Session interface {
+	// Player is the robot of this game, 0 or 1
+	Player() int
+	// Seed is the same on both games, so they simulate the same waves
+	Seed() uint64
+	Send(frame int, input Input) error
+	// Input returns the input of the other game for the frame, false if it has not arrived yet
+	Input(frame int) (Input, bool)
+	// Err returns why the session ended, nil while it is open
+	Err() error
+	Close()
+}
+
+type session struct {
+	conn   *websocket.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+	player int
+	seed   uint64
+
+	mu     sync.Mutex
+	inputs map[int]Input
+	err    error
+}
+
+// Join connects to the relay and waits for a second game in the room, it blocks until both are paired
+func Join(ctx context.Context, relayUrl, room string) (Session, error) {
+	conn, _, err := websocket.Dial(ctx, relayUrl+"?"+url.Values{"room": {room}}.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the relay: %w", err)
+	}
+
+	var start Message
+	if err := wsjson.Read(ctx, conn, &start); err != nil {
+		conn.CloseNow()
+		return nil, fmt.Errorf("cannot join the room: %w", err)
+	}
+
+	if start.Type != TypeStart {
+		conn.CloseNow()
+		return nil, fmt.Errorf("unexpected message: %s", start.Type)
+	}
+
+	s := &session{
+		conn:   conn,
+		player: start.Player,
+		seed:   start.Seed,
+		inputs: map[int]Input{},
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.read()
+
+	return s, nil
+}
+
+func (s *session) Player() int {
+	return s.player
+}
+
+func (s *session) Seed() uint64 {
+	return s.seed
+}
+
+func (s *session) Send(frame int, input Input) error {
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, writeTimeout)
+	defer cancel()
+
+	if err := wsjson.Write(ctx, s.conn, Message{Type: TypeInput, Frame: frame, Input: input}); err != nil {
+		s.fail(fmt.Errorf("cannot send the input: %w", err))
+		return s.Err()
+	}
+
+	return nil
+}
+
+// Input returns the input once, it is forgotten after it has been played
+func (s *session) Input(frame int) (Input, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	input, ok := s.inputs[frame]
+	delete(s.inputs, frame)
+
+	return input, ok
+}
+
+func (s *session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *session) Close() {
+	s.fail(ErrClosed)
+	s.cancel()
+	s.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func (s *session) read() {
+	for {
+		var message Message
+		if err := wsjson.Read(s.ctx, s.conn, &message); err != nil {
+			s.fail(ErrClosed)
+			return
+		}
+
+		if message.Type == TypeInput {
+			s.mu.Lock()
+			s.inputs[message.Frame] = message.Input
+			s.mu.Unlock()
+		}
+	}
+}
+
+// fail keeps the first error, the later ones are caused by it
+func (s *session) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
@@ -0,0 +1,143 @@
+//go:build !js
+
+// Package relay pairs the games joining the same room and forwards the messages between them
+package relay
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"spaceinvader/internal/netplay"
+	"sync"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// waiting is the first game of a room, until a second one joins
+type waiting struct {
+	conn    *websocket.Conn
+	partner chan *websocket.Conn
+}
+
+type relay struct {
+	mu      sync.Mutex
+	waiting map[string]*waiting
+	// origins are the host patterns of the pages the games are served from, the desktop client sends no origin
+	origins []string
+}
+
+// New returns the WebSocket handler of the relay, the room is given in the query
+func New(origins []string) http.Handler {
+	return &relay{waiting: map[string]*waiting{}, origins: origins}
+}
+
+func (r *relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	conn, err := websocket.Accept(w, req, &websocket.AcceptOptions{OriginPatterns: r.origins})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.CloseNow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages := make(chan []byte)
+	go read(ctx, conn, messages)
+
+	partner, ok := r.pair(ctx, req.URL.Query().Get("room"), conn, messages)
+	if !ok {
+		return
+	}
+
+	// Closing this connection on return also ends the handler of the partner
+	for data := range messages {
+		if err := partner.Write(ctx, websocket.MessageText, data); err != nil {
+			return
+		}
+	}
+}
+
+// pair waits for a second game in the room, the second one to join starts the game of both.
+// The partner is handed over before the starts are sent, the first game plays as soon as it gets its start
+func (r *relay) pair(ctx context.Context, room string, conn *websocket.Conn, messages chan []byte) (*websocket.Conn, bool) {
+	r.mu.Lock()
+	first, ok := r.waiting[room]
+	if ok {
+		delete(r.waiting, room)
+		r.mu.Unlock()
+		first.partner <- conn
+
+		// The second game starts first, so the inputs of the first game only reach it after its start
+		seed := rand.Uint64()
+		if err := start(ctx, conn, seed, 1); err != nil {
+			fmt.Println(err)
+			first.conn.CloseNow()
+			return nil, false
+		}
+		if err := start(ctx, first.conn, seed, 0); err != nil {
+			fmt.Println(err)
+			return nil, false
+		}
+
+		return first.conn, true
+	}
+
+	w := &waiting{conn: conn, partner: make(chan *websocket.Conn, 1)}
+	r.waiting[room] = w
+	r.mu.Unlock()
+
+	select {
+	case partner := <-w.partner:
+		return partner, true
+	case data, open := <-messages:
+		r.mu.Lock()
+		left := r.waiting[room] == w
+		if left {
+			delete(r.waiting, room)
+		}
+		r.mu.Unlock()
+
+		if left {
+			// The game left, or sent an input before the start
+			return nil, false
+		}
+
+		// A partner joined meanwhile, the game may have started and the message is its first input
+		partner := <-w.partner
+		if open {
+			if err := partner.Write(ctx, websocket.MessageText, data); err != nil {
+				return nil, false
+			}
+		}
+
+		return partner, true
+	}
+}
+
+func start(ctx context.Context, conn *websocket.Conn, seed uint64, player int) error {
+	if err := wsjson.Write(ctx, conn, netplay.Message{Type: netplay.TypeStart, Seed: seed, Player: player}); err != nil {
+		return fmt.Errorf("cannot start the game: %w", err)
+	}
+
+	return nil
+}
+
+// read passes the messages of the game on, the channel is closed once the game is gone
+func read(ctx context.Context, conn *websocket.Conn, messages chan []byte) {
+	defer close(messages)
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		select {
+		case messages <- data:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
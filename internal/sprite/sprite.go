@@ -1,7 +1,11 @@
 package sprite
 
 import (
+	"fmt"
+	"image"
+	_ "image/png"
 	"math"
+	"os"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -15,6 +19,20 @@ type imgData struct {
 
 var imgCache = map[string]imgData{}
 
+// sizeCache has the sizes of the images fitted without loading them, when running headless
+var sizeCache = map[string]imgData{}
+
+var headless bool
+
+// SetHeadless disables image loading and drawing, sprites still move, animate and collide
+// so the game can be simulated without a window. It returns the previous setting to restore it
+func SetHeadless(enabled bool) bool {
+	previous := headless
+	headless = enabled
+
+	return previous
+}
+
 func New(imageNames []string, w, h int, options SpiteOptions) Sprite {
 	if len(imageNames) == 0 {
 		panic("Sprite require at least one image")
@@ -35,7 +53,13 @@ func New(imageNames []string, w, h int, options SpiteOptions) Sprite {
 }
 
 func RescaleImageToFit(imageName string, targetWidth, targetHeight int) (*ebiten.Image, int, int, error) {
-	if imgData, ok := imgCache[imageName]; ok {
+	// The same image can be used in different sizes
+	cacheKey := fmt.Sprintf("%s@%dx%d", imageName, targetWidth, targetHeight)
+	if headless {
+		width, height, err := headlessSize(cacheKey, imageName, targetWidth, targetHeight)
+		return nil, width, height, err
+	}
+	if imgData, ok := imgCache[cacheKey]; ok {
 		return imgData.image, imgData.w, imgData.h, nil
 	}
 
@@ -45,15 +69,7 @@ func RescaleImageToFit(imageName string, targetWidth, targetHeight int) (*ebiten
 	}
 
 	bounds := img.Bounds()
-	origWidth := bounds.Dx()
-	origHeight := bounds.Dy()
-
-	widthRatio := float64(targetWidth) / float64(origWidth)
-	heightRatio := float64(targetHeight) / float64(origHeight)
-
-	scale := math.Min(widthRatio, heightRatio)
-	newWidth := int(float64(origWidth) * scale)
-	newHeight := int(float64(origHeight) * scale)
+	scale, newWidth, newHeight := fit(bounds.Dx(), bounds.Dy(), targetWidth, targetHeight)
 
 	rescaled := ebiten.NewImage(newWidth, newHeight)
 
@@ -64,7 +80,7 @@ func RescaleImageToFit(imageName string, targetWidth, targetHeight int) (*ebiten
 		GeoM: geom,
 	})
 
-	imgCache[imageName] = imgData{
+	imgCache[cacheKey] = imgData{
 		image: rescaled,
 		w:     newWidth,
 		h:     newHeight,
@@ -73,6 +89,39 @@ func RescaleImageToFit(imageName string, targetWidth, targetHeight int) (*ebiten
 	return rescaled, newWidth, newHeight, nil
 }
 
+// headlessSize is the size the image is fitted to, read from its header as the image isn't loaded
+func headlessSize(cacheKey, imageName string, targetWidth, targetHeight int) (int, int, error) {
+	if size, ok := sizeCache[cacheKey]; ok {
+		return size.w, size.h, nil
+	}
+
+	file, err := os.Open(imageName)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, newWidth, newHeight := fit(config.Width, config.Height, targetWidth, targetHeight)
+	sizeCache[cacheKey] = imgData{w: newWidth, h: newHeight}
+
+	return newWidth, newHeight, nil
+}
+
+// fit scales the size to fit in the target, keeping its aspect ratio
+func fit(origWidth, origHeight, targetWidth, targetHeight int) (float64, int, int) {
+	widthRatio := float64(targetWidth) / float64(origWidth)
+	heightRatio := float64(targetHeight) / float64(origHeight)
+
+	scale := math.Min(widthRatio, heightRatio)
+
+	return scale, int(float64(origWidth) * scale), int(float64(origHeight) * scale)
+}
+
 type Sprite interface {
 	Render(screen *ebiten.Image)
 	SetId(int)
@@ -90,6 +139,8 @@ type Sprite interface {
 	Soft(soft bool)
 	IsMoving() bool
 	RunAfterAnimation()
+	InAfterAnimation() bool
+	SetImages(imageNames []string)
 	Close()
 }
 
@@ -125,6 +176,7 @@ type SpiteOptions struct {
 	AnimateOnMove                bool
 	CollisionSprites             []Sprite
 	CollisionCallback            func(Sprite, []Sprite)
+	CollisionMargin              float64
 	AfterAnimationAnimationDelay int
 	AfterAnimationImages         []*ebiten.Image
 	AfterAnimationCallback       func(Sprite)
@@ -174,7 +226,7 @@ func (s *sprite) Render(screen *ebiten.Image) {
 			return
 		}
 
-		screen.DrawImage(s.options.AfterAnimationImages[s.afterAnimationImageId], op)
+		s.draw(screen, s.options.AfterAnimationImages[s.afterAnimationImageId], op)
 		if s.afterAnimationImageDelayCnt == s.options.AfterAnimationAnimationDelay {
 			s.afterAnimationImageId++
 			s.afterAnimationImageDelayCnt = 0
@@ -185,18 +237,45 @@ func (s *sprite) Render(screen *ebiten.Image) {
 		return
 	}
 
-	screen.DrawImage(s.imgs[s.imgIndex], op)
+	s.draw(screen, s.imgs[s.imgIndex], op)
 	s.collisionDetection()
 
 	s.correctSoftPos()
 	s.stepToNextImage()
 }
 
+// draw skips drawing when there is no screen or image, which is the case when running headless
+func (s *sprite) draw(screen, img *ebiten.Image, op *ebiten.DrawImageOptions) {
+	if screen == nil || img == nil {
+		return
+	}
+
+	screen.DrawImage(img, op)
+}
+
 func (s *sprite) RunAfterAnimation() {
 	s.afterAnimationImageId = 0
 	s.inAfterAnimation = true
 }
 
+func (s *sprite) InAfterAnimation() bool {
+	return s.inAfterAnimation
+}
+
+// SetImages replaces the animation images, they are fitted to the current size of the sprite
+func (s *sprite) SetImages(imageNames []string) {
+	if len(imageNames) == 0 {
+		return
+	}
+
+	s.imgs = nil
+	for _, imageName := range imageNames {
+		img, _, _, _ := RescaleImageToFit(imageName, s.width, s.height)
+		s.imgs = append(s.imgs, img)
+	}
+	s.imgIndex = 0
+}
+
 func (s *sprite) SetId(id int) {
 	if s.closed {
 		return
@@ -308,12 +387,13 @@ func (s *sprite) isCollided(otherSprite Sprite) bool {
 
 	otherSpriteX := otherSprite.GetX()
 	otherSpriteY := otherSprite.GetY()
+	margin := s.options.CollisionMargin
 
-	if s.currX > otherSpriteX+otherSprite.GetWidth() || s.currX+float64(s.width) < otherSpriteX {
+	if s.currX+margin > otherSpriteX+otherSprite.GetWidth() || s.currX+float64(s.width)-margin < otherSpriteX {
 		return false
 	}
 
-	if s.currY > otherSpriteY+otherSprite.GetHeight() || s.currY+float64(s.height) < otherSpriteY {
+	if s.currY+margin > otherSpriteY+otherSprite.GetHeight() || s.currY+float64(s.height)-margin < otherSpriteY {
 		return false
 	}
 
@@ -332,14 +412,16 @@ func (s *sprite) correctSoftPos() {
 
 		s.currX += (s.x - s.currX) / s.options.SoftX
 		// s.currY += (s.y - s.currY) / s.options.SoftY
-		s.currY += s.options.SoftY * 5 / (s.y - s.currY)
-
-		if math.Abs(s.x-s.currX) < 0 {
-			s.currX = s.x
+		// The step grows as the sprite gets closer, it stops at the target instead of swinging around it
+		step := s.options.SoftY * 5 / (s.y - s.currY)
+		if math.Abs(step) >= math.Abs(s.y-s.currY) {
+			s.currY = s.y
+		} else {
+			s.currY += step
 		}
 
-		if math.Abs(s.y-s.currY) < 0 {
-			s.currY = s.y
+		if math.Abs(s.x-s.currX) < 1 {
+			s.currX = s.x
 		}
 
 		return
@@ -0,0 +1,61 @@
+//go:build !js
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type fileStorage struct {
+	dir string
+}
+
+// New returns a storage writing to the user config directory
+func New() Storage {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return &fileStorage{dir: filepath.Join(dir, appName)}
+}
+
+func (s *fileStorage) Load(key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot load %s: %w", key, err)
+	}
+
+	return data, nil
+}
+
+func (s *fileStorage) Save(key string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("cannot save %s: %w", key, err)
+	}
+
+	if err := os.WriteFile(s.path(key), data, 0o644); err != nil {
+		return fmt.Errorf("cannot save %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *fileStorage) Remove(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot remove %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *fileStorage) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
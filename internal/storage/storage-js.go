@@ -0,0 +1,60 @@
+//go:build js
+
+package storage
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+type localStorage struct{}
+
+// New returns a storage using the local storage of the browser
+func New() Storage {
+	return &localStorage{}
+}
+
+func (s *localStorage) Load(key string) ([]byte, error) {
+	store, err := s.store()
+	if err != nil {
+		return nil, err
+	}
+
+	value := store.Call("getItem", appName+"."+key)
+	if value.IsNull() {
+		return nil, nil
+	}
+
+	return []byte(value.String()), nil
+}
+
+func (s *localStorage) Save(key string, data []byte) error {
+	store, err := s.store()
+	if err != nil {
+		return err
+	}
+
+	store.Call("setItem", appName+"."+key, string(data))
+
+	return nil
+}
+
+func (s *localStorage) Remove(key string) error {
+	store, err := s.store()
+	if err != nil {
+		return err
+	}
+
+	store.Call("removeItem", appName+"."+key)
+
+	return nil
+}
+
+func (s *localStorage) store() (js.Value, error) {
+	store := js.Global().Get("localStorage")
+	if store.IsUndefined() || store.IsNull() {
+		return js.Value{}, errors.New("local storage is not available")
+	}
+
+	return store, nil
+}
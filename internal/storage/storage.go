@@ -0,0 +1,13 @@
+// Package storage keeps small pieces of player data on the device
+package storage
+
+const appName = "spaceinvader"
+
+// Storage saves data by key, in a file on desktop and in the local storage of the browser
+type Storage interface {
+	// Load returns nil if nothing is saved with the key
+	Load(key string) ([]byte, error)
+	Save(key string, data []byte) error
+	// Remove deletes the data of the key, it's not an error if nothing is saved
+	Remove(key string) error
+}